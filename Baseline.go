@@ -0,0 +1,125 @@
+package godbhelper
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+//Baseline marks every added chain as already updated to version without running
+//any query. This is intended for databases created before using dbhelper.
+//Pass the names of tables which have to exist for the baseline to succeed.
+//Chains which already have a version can't be baselined
+func (dbhelper *DBhelper) Baseline(version Version, expectedTables ...string) error {
+	var chains []string
+	for _, chain := range dbhelper.QueryChains {
+		chains = append(chains, chain.Name)
+	}
+	return dbhelper.baseline(chains, version, expectedTables)
+}
+
+//BaselineChain like Baseline but only for the chain with the given name
+func (dbhelper *DBhelper) BaselineChain(chain string, version Version, expectedTables ...string) error {
+	return dbhelper.baseline([]string{chain}, version, expectedTables)
+}
+
+//baseline stores version for chains in one transaction
+func (dbhelper *DBhelper) baseline(chains []string, version Version, expectedTables []string) error {
+	if !dbhelper.Options.StoreVersionInDB {
+		return ErrCantStoreVersionInDB
+	}
+	if err := version.Valid(); err != nil {
+		return err
+	}
+
+	unlock, err := dbhelper.lockMigrations()
+	if err != nil {
+		return dbhelper.handleErrHook(err, "locking migrations")
+	}
+	defer unlock()
+
+	if err = dbhelper.initDBVersion(); err != nil {
+		return dbhelper.handleErrHook(err, "loading versions")
+	}
+
+	if err = dbhelper.checkTablesExist(expectedTables); err != nil {
+		return err
+	}
+
+	for _, chain := range chains {
+		if current := dbhelper.ChainVersion(chain); NoVersion.Less(current) {
+			return fmt.Errorf("%w: chain '%s' v.%s", ErrAlreadyVersioned, chain, current)
+		}
+	}
+
+	tx, err := dbhelper.DB.Beginx()
+	if err != nil {
+		return dbhelper.handleErrHook(err, "beginning transaction")
+	}
+
+	for _, chain := range chains {
+		statement := fmt.Sprintf("-- baseline v.%s", version)
+		err = dbhelper.saveVersion(tx, chain, version)
+		if err == nil {
+			err = dbhelper.addMigrationRecord(tx, MigrationRecord{
+				Chain:     chain,
+				Version:   version,
+				Kind:      MigrationBaseline,
+				Checksum:  checksum(statement),
+				Statement: statement,
+				AppliedAt: time.Now(),
+				Success:   true,
+			})
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return dbhelper.handleErrHook(err, "baselining chain "+chain)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return dbhelper.handleErrHook(err, "committing baseline")
+	}
+
+	for _, chain := range chains {
+		dbhelper.setChainVersion(chain, version)
+		if dbhelper.Options.Debug {
+			fmt.Printf("Baselined chain '%s' at v.%s\n", chain, version)
+		}
+	}
+
+	return nil
+}
+
+//checkTablesExist returns ErrMissingTables if one of tables doesn't exist
+func (dbhelper *DBhelper) checkTablesExist(tables []string) error {
+	if len(tables) == 0 {
+		return nil
+	}
+
+	existing, err := dbhelper.listTables()
+	if err != nil {
+		return dbhelper.handleErrHook(err, "listing tables")
+	}
+
+	var missing []string
+	for _, table := range tables {
+		found := false
+		for _, name := range existing {
+			if strings.EqualFold(name, table) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, table)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingTables, strings.Join(missing, ", "))
+	}
+	return nil
+}
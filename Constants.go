@@ -13,9 +13,43 @@ const (
 	Postgres
 )
 
+//TransactionMode specifies how RunUpdate wraps queries into transactions.
+//MySQL commits implicitly before and after DDL statements like CREATE, ALTER
+//or DROP TABLE. On MySQL a failed step can't roll back the DDL statements it
+//already ran and the stored version stays at the last completed step, so
+//keep every version to a single DDL statement there
+type TransactionMode uint8
+
+const (
+	//TxPerVersion runs all queries of one version of a chain in a transaction
+	TxPerVersion TransactionMode = iota
+	//TxPerChain runs all pending queries of a chain in one transaction
+	TxPerChain
+)
+
+//ExportFormat the file format of an exported QueryChain
+type ExportFormat uint8
+
+const (
+	//FormatAuto detects the format by the file extension. JSON is used for unknown extensions
+	FormatAuto ExportFormat = iota
+	//FormatJSON indented JSON
+	FormatJSON
+	//FormatYAML YAML with statements as block scalars
+	FormatYAML
+	//FormatTOML TOML with statements as multiline strings
+	FormatTOML
+)
+
 const (
 	//TableDBVersion tableName for db version store
 	TableDBVersion = "DBVersion"
+	//TableMigrationHistory tableName for the executed queries
+	TableMigrationHistory = "DBMigrationHistory"
+	//TableMigrationLock tableName for the migration lock of sqlite
+	TableMigrationLock = "DBMigrationLock"
+	//TableRepeatable tableName for the checksums of repeatable queries
+	TableRepeatable = "DBRepeatable"
 )
 
 const (
@@ -0,0 +1,92 @@
+package godbhelper
+
+import (
+	"fmt"
+	"strings"
+)
+
+//sortByDependencies orders chains so that every chain comes after its dependencies.
+//Otherwise the order of chains is kept
+func sortByDependencies(chains []QueryChain) ([]QueryChain, error) {
+	index := make(map[string]int, len(chains))
+	for i, chain := range chains {
+		index[chain.Name] = i
+	}
+
+	for _, chain := range chains {
+		for _, dependency := range chain.DependsOn {
+			if _, ok := index[dependency]; !ok {
+				return nil, fmt.Errorf("%w: chain '%s' depends on '%s'", ErrMissingDependency, chain.Name, dependency)
+			}
+		}
+	}
+
+	//Repeatedly take the first chain whose dependencies are done
+	sorted := make([]QueryChain, 0, len(chains))
+	done := make([]bool, len(chains))
+	for len(sorted) < len(chains) {
+		next := -1
+		for i, chain := range chains {
+			if !done[i] && dependenciesDone(chain, index, done) {
+				next = i
+				break
+			}
+		}
+
+		if next == -1 {
+			return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(dependencyCycle(chains, index, done), " -> "))
+		}
+
+		done[next] = true
+		sorted = append(sorted, chains[next])
+	}
+
+	return sorted, nil
+}
+
+//dependenciesDone returns true if all dependencies of chain are done
+func dependenciesDone(chain QueryChain, index map[string]int, done []bool) bool {
+	for _, dependency := range chain.DependsOn {
+		if !done[index[dependency]] {
+			return false
+		}
+	}
+	return true
+}
+
+//dependencyCycle returns the names of a cycle of chains which aren't done
+func dependencyCycle(chains []QueryChain, index map[string]int, done []bool) []string {
+	//Every remaining chain has a remaining dependency, so following
+	//them has to visit a chain twice
+	var path []int
+	visited := make(map[int]int)
+	current := -1
+	for i := range chains {
+		if !done[i] {
+			current = i
+			break
+		}
+	}
+
+	for {
+		if start, ok := visited[current]; ok {
+			path = append(path[start:], current)
+			break
+		}
+		visited[current] = len(path)
+		path = append(path, current)
+
+		for _, dependency := range chains[current].DependsOn {
+			if !done[index[dependency]] {
+				current = index[dependency]
+				break
+			}
+		}
+	}
+
+	names := make([]string, len(path))
+	for i, chain := range path {
+		names[i] = chains[chain].Name
+	}
+	return names
+}
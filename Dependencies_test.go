@@ -0,0 +1,82 @@
+package godbhelper
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+//chains creates QueryChains from names like "b:a,c" (b depends on a and c)
+func chains(definitions ...string) []QueryChain {
+	var chains []QueryChain
+	for _, definition := range definitions {
+		name, dependencies, _ := strings.Cut(definition, ":")
+		chain := QueryChain{Name: name}
+		if len(dependencies) > 0 {
+			chain.DependsOn = strings.Split(dependencies, ",")
+		}
+		chains = append(chains, chain)
+	}
+	return chains
+}
+
+func TestSortByDependencies(t *testing.T) {
+	tests := []struct {
+		name   string
+		chains []QueryChain
+		want   []string
+	}{
+		{name: "no chains", chains: nil, want: []string{}},
+		{name: "order is kept", chains: chains("a", "b", "c"), want: []string{"a", "b", "c"}},
+		{name: "dependency moves chain back", chains: chains("b:a", "a"), want: []string{"a", "b"}},
+		{name: "chain", chains: chains("c:b", "b:a", "a"), want: []string{"a", "b", "c"}},
+		{name: "diamond", chains: chains("d:b,c", "c:a", "b:a", "a"), want: []string{"a", "c", "b", "d"}},
+		{name: "independent chains keep their order", chains: chains("x", "b:a", "y", "a"), want: []string{"x", "y", "a", "b"}},
+		{name: "dependency listed twice", chains: chains("b:a,a", "a"), want: []string{"a", "b"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sorted, err := sortByDependencies(test.chains)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, len(sorted))
+			for i, chain := range sorted {
+				got[i] = chain.Name
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSortByDependenciesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		chains []QueryChain
+		err    error
+		want   string
+	}{
+		{name: "missing dependency", chains: chains("a", "b:c"), err: ErrMissingDependency, want: "chain 'b' depends on 'c'"},
+		{name: "self dependency", chains: chains("a:a"), err: ErrDependencyCycle, want: "a -> a"},
+		{name: "two chains", chains: chains("a:b", "b:a"), err: ErrDependencyCycle, want: "a -> b -> a"},
+		{name: "three chains", chains: chains("a:c", "b:a", "c:b"), err: ErrDependencyCycle, want: "a -> c -> b -> a"},
+		{name: "cycle behind a chain", chains: chains("x", "a:b", "b:c", "c:b"), err: ErrDependencyCycle, want: "b -> c -> b"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := sortByDependencies(test.chains)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+			if !strings.HasSuffix(err.Error(), test.want) {
+				t.Errorf("got %q, want suffix %q", err.Error(), test.want)
+			}
+		})
+	}
+}
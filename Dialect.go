@@ -0,0 +1,181 @@
+package godbhelper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+//dialectNames the names of the database systems used as keys of SQLQuery.Variants
+var dialectNames = map[dbsys]string{
+	Sqlite:          "sqlite",
+	SqliteEncrypted: "sqlite-encrypted",
+	Mysql:           "mysql",
+	Postgres:        "postgres",
+}
+
+//dialectQueries returns queries using the variants for the database system of dbhelper
+func (dbhelper *DBhelper) dialectQueries(queries []SQLQuery) []SQLQuery {
+	return dialectQueriesFor(dbhelper.dbKind, queries)
+}
+
+//dialectQueriesFor returns queries using the variants for dbKind
+func dialectQueriesFor(dbKind dbsys, queries []SQLQuery) []SQLQuery {
+	resolved := make([]SQLQuery, len(queries))
+	for i := range queries {
+		resolved[i] = queries[i].forDialect(dbKind)
+	}
+	return resolved
+}
+
+//columnTypes returns the lowercased types of the columns of table by their names.
+//The map is empty if the table doesn't exist
+func (dbhelper *DBhelper) columnTypes(table string) (map[string]string, error) {
+	var query string
+	switch dbhelper.dbKind {
+	case Sqlite, SqliteEncrypted:
+		query = "SELECT name, type FROM pragma_table_info(?)"
+	case Mysql:
+		query = "SELECT COLUMN_NAME, DATA_TYPE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?"
+	case Postgres:
+		//Postgres folds unquoted identifiers to lowercase
+		table = strings.ToLower(table)
+		query = "SELECT column_name, data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1"
+	default:
+		return nil, ErrDBNotSupported
+	}
+
+	rows, err := dbhelper.DB.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]string)
+	for rows.Next() {
+		var name, colType string
+		if err := rows.Scan(&name, &colType); err != nil {
+			return nil, err
+		}
+		columns[strings.ToLower(name)] = strings.ToLower(colType)
+	}
+
+	return columns, rows.Err()
+}
+
+//quoteIdentifier quotes the table or column name for the used database
+func (dbhelper *DBhelper) quoteIdentifier(name string) string {
+	if dbhelper.dbKind == Mysql {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+//listTables returns the names of all tables created by the user
+func (dbhelper *DBhelper) listTables() ([]string, error) {
+	var query string
+	switch dbhelper.dbKind {
+	case Sqlite, SqliteEncrypted:
+		query = "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'"
+	case Mysql:
+		query = "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'"
+	case Postgres:
+		query = "SELECT tablename FROM pg_tables WHERE schemaname = current_schema()"
+	default:
+		return nil, ErrDBNotSupported
+	}
+
+	var tables []string
+	err := dbhelper.DB.Select(&tables, query)
+	return tables, err
+}
+
+//listViews returns the names of all views created by the user
+func (dbhelper *DBhelper) listViews() ([]string, error) {
+	var query string
+	switch dbhelper.dbKind {
+	case Sqlite, SqliteEncrypted:
+		query = "SELECT name FROM sqlite_master WHERE type = 'view'"
+	case Mysql:
+		query = "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'VIEW'"
+	case Postgres:
+		query = "SELECT viewname FROM pg_views WHERE schemaname = current_schema()"
+	default:
+		return nil, ErrDBNotSupported
+	}
+
+	var views []string
+	err := dbhelper.DB.Select(&views, query)
+	return views, err
+}
+
+//dropAllTables drops every view and table created by the user, including their
+//triggers and indexes. Other objects like sequences, functions or procedures
+//which don't belong to a table are kept. Foreign key checks are disabled while
+//dropping, so the tables can be dropped in any order
+func (dbhelper *DBhelper) dropAllTables() error {
+	views, err := dbhelper.listViews()
+	if err != nil {
+		return err
+	}
+	tables, err := dbhelper.listTables()
+	if err != nil || len(views)+len(tables) == 0 {
+		return err
+	}
+
+	//Foreign key settings are per connection
+	ctx := context.Background()
+	conn, err := dbhelper.DB.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var getFK, setFK, dropViewFormat, dropFormat string
+	switch dbhelper.dbKind {
+	case Sqlite, SqliteEncrypted:
+		getFK, setFK = "PRAGMA foreign_keys", "PRAGMA foreign_keys = %d"
+		dropViewFormat, dropFormat = "DROP VIEW IF EXISTS %s", "DROP TABLE IF EXISTS %s"
+	case Mysql:
+		getFK, setFK = "SELECT @@SESSION.FOREIGN_KEY_CHECKS", "SET FOREIGN_KEY_CHECKS = %d"
+		dropViewFormat, dropFormat = "DROP VIEW IF EXISTS %s", "DROP TABLE IF EXISTS %s"
+	case Postgres:
+		dropViewFormat, dropFormat = "DROP VIEW IF EXISTS %s CASCADE", "DROP TABLE IF EXISTS %s CASCADE"
+	}
+
+	//Restore the previous setting, the connection goes back into the pool
+	if len(getFK) > 0 {
+		var fkChecks int
+		if err = conn.GetContext(ctx, &fkChecks, getFK); err != nil {
+			return err
+		}
+		if _, err = conn.ExecContext(ctx, fmt.Sprintf(setFK, 0)); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, fmt.Sprintf(setFK, fkChecks))
+	}
+
+	var queries []string
+	for _, view := range views {
+		queries = append(queries, fmt.Sprintf(dropViewFormat, dbhelper.quoteIdentifier(view)))
+	}
+	for _, table := range tables {
+		//The lock is held by the running update
+		if table == TableMigrationLock {
+			continue
+		}
+		queries = append(queries, fmt.Sprintf(dropFormat, dbhelper.quoteIdentifier(table)))
+	}
+
+	for _, query := range queries {
+		if _, err = conn.ExecContext(ctx, query); err != nil {
+			return err
+		}
+
+		if dbhelper.Options.Debug {
+			fmt.Println(query)
+		}
+	}
+
+	return nil
+}
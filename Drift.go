@@ -0,0 +1,99 @@
+package godbhelper
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+//DriftError an already applied query was changed afterwards
+type DriftError struct {
+	Chain   string
+	Version Version
+	//Index the position of the query within the queries of its version
+	Index int
+	//Applied checksum of the applied statement
+	Applied string
+	//Current checksum of the statement in the chain
+	Current string
+}
+
+func (err *DriftError) Error() string {
+	return fmt.Sprintf("Query %d of chain '%s' v.%s was changed after it was applied", err.Index, err.Chain, err.Version)
+}
+
+//checkDrift compares the applied queries of every chain with the checksums
+//of the migration history. Queries applied before the history existed are skipped.
+//The first drift is returned as error unless Options.WarnOnDrift is set. Drifts
+//are returned then and passed to the ErrHook. Debug prints them too
+func (dbhelper *DBhelper) checkDrift() ([]*DriftError, error) {
+	var drifts []*DriftError
+	for _, chain := range dbhelper.QueryChains {
+		chainDrifts, err := dbhelper.chainDrifts(chain)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, drift := range chainDrifts {
+			if !dbhelper.Options.WarnOnDrift {
+				return nil, drift
+			}
+
+			dbhelper.handleErrHook(drift, "query changed after it was applied")
+			if dbhelper.Options.Debug {
+				fmt.Println(color.New(color.FgYellow).SprintFunc()("Warning: " + drift.Error()))
+			}
+		}
+		drifts = append(drifts, chainDrifts...)
+	}
+
+	return drifts, nil
+}
+
+//chainDrifts returns all applied queries of chain which differ from the migration history
+func (dbhelper *DBhelper) chainDrifts(chain QueryChain) ([]*DriftError, error) {
+	version := dbhelper.ChainVersion(chain.Name)
+
+	records, err := dbhelper.MigrationHistory(chain.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	//Use the checksum of the latest successful run of each query
+	applied := make(map[string]string)
+	for _, record := range records {
+		if record.Success && record.Kind == MigrationUp {
+			applied[record.Version.canonical()+"/"+fmt.Sprint(record.Index)] = record.Checksum
+		}
+	}
+
+	var queries []SQLQuery
+	for _, query := range dbhelper.versionedQueries(chain) {
+		if query.hasUp() && !version.Less(query.VersionAdded) {
+			queries = append(queries, query)
+		}
+	}
+
+	var drifts []*DriftError
+	for _, group := range splitByVersion(queries) {
+		for i, query := range group {
+			sum, ok := applied[query.VersionAdded.canonical()+"/"+fmt.Sprint(i)]
+			//The code of a Func can't be compared
+			if !ok || query.Func != nil {
+				continue
+			}
+
+			if current := checksum(query.sql()); current != sum {
+				drifts = append(drifts, &DriftError{
+					Chain:   chain.Name,
+					Version: query.VersionAdded,
+					Index:   i,
+					Applied: sum,
+					Current: current,
+				})
+			}
+		}
+	}
+
+	return drifts, nil
+}
@@ -25,11 +25,17 @@ func (dbhelper *DBhelper) handleErrHook(err error, content string) error {
 		dbhelper.NextErrHookOption = nil
 	}
 
+	//SetErrHook doesn't require options
+	var prefix string
+	if options != nil {
+		prefix = options.Prefix
+	}
+
 	//Call the correct hook
 	if dbhelper.NextErrHookFunc == nil {
-		dbhelper.ErrHookFunc(err, content, options.Prefix)
+		dbhelper.ErrHookFunc(err, content, prefix)
 	} else {
-		dbhelper.NextErrHookFunc(err, content, options.Prefix)
+		dbhelper.NextErrHookFunc(err, content, prefix)
 		dbhelper.NextErrHookFunc = nil
 	}
 
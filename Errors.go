@@ -1,6 +1,10 @@
 package godbhelper
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
 	//ErrDBNotSupported error if database is not supported
@@ -27,6 +31,69 @@ var (
 	//ErrCantStoreVersionInDB err if running update and StoreVersionInDB=false
 	ErrCantStoreVersionInDB = errors.New("Can't store Version in Database. Set StoreVersionInDB=true")
 
+	//ErrNoDownQuery if a version of a chain has no down statement to undo it
+	ErrNoDownQuery = errors.New("Version can't be undone. No down statement found")
+
+	//ErrInvalidVersion if a version can't be parsed
+	ErrInvalidVersion = errors.New("Invalid version")
+
+	//ErrInvalidMigrationFile if a file of a migration directory has an invalid name
+	ErrInvalidMigrationFile = errors.New("Invalid migration file")
+
+	//ErrDuplicateMigrationVersion if a migration directory contains a version twice
+	ErrDuplicateMigrationVersion = errors.New("Duplicate migration version")
+
+	//ErrInvalidSQLFile if a .sql file can't be split into statements
+	ErrInvalidSQLFile = errors.New("Invalid .sql file")
+
+	//ErrLockTimeout if the migration lock wasn't released by another process in time
+	ErrLockTimeout = errors.New("Timeout waiting for migration lock")
+
+	//ErrInvalidExportFormat if an unknown ExportFormat is used
+	ErrInvalidExportFormat = errors.New("Invalid export format")
+
+	//ErrInvalidQueryParam if a restored param has an unsupported type
+	ErrInvalidQueryParam = errors.New("Invalid query param")
+
+	//ErrAlreadyVersioned if Baseline is used for a chain which already has a version
+	ErrAlreadyVersioned = errors.New("Chain already has a version")
+
+	//ErrMissingTables if tables expected by Baseline don't exist
+	ErrMissingTables = errors.New("Expected tables are missing")
+
+	//ErrMissingDependency if a QueryChain depends on a chain which wasn't added
+	ErrMissingDependency = errors.New("Missing chain dependency")
+
+	//ErrDependencyCycle if QueryChains depend on each other
+	ErrDependencyCycle = errors.New("Chain dependency cycle")
+
+	//ErrUnnamedRepeatable if a repeatable SQLQuery has no name
+	ErrUnnamedRepeatable = errors.New("Repeatable query without name")
+
+	//ErrFuncNotScriptable if a query written in Go gets exported as script
+	ErrFuncNotScriptable = errors.New("Go migrations can't be exported as script")
+
+	//ErrDirtyMigration if the version table of another migration tool has a failed migration
+	ErrDirtyMigration = errors.New("Dirty migration")
+
+	//ErrEmptyQuery if a SQLQuery has no statement
+	ErrEmptyQuery = errors.New("Query has no statement")
+
+	//ErrFparamsMismatch if the number of Fparams doesn't match the format verbs of a query
+	ErrFparamsMismatch = errors.New("Wrong number of Fparams")
+
+	//ErrAmbiguousVersion if a chain spells the same version differently (eg. "1" and "1.0")
+	ErrAmbiguousVersion = errors.New("Version spelled differently")
+
+	//ErrDuplicateChain if two QueryChains have the same name
+	ErrDuplicateChain = errors.New("Duplicate chain name")
+
+	//ErrInvalidVariant if a SQLQuery has a variant for an unknown database system
+	ErrInvalidVariant = errors.New("Invalid query variant")
+
+	//ErrLegacyVersionOrder if versions of a chain are ordered differently as floats of older releases
+	ErrLegacyVersionOrder = errors.New("Versions are ordered differently than in older releases")
+
 	//ErrInvalidDatabase an invalid dbsys was used
 	ErrInvalidDatabase = errors.New("Invalid database")
 
@@ -41,3 +108,111 @@ var (
 	//ErrCantAddress if input is no pointer
 	ErrCantAddress = errors.New("Can't address value")
 )
+
+//QueryError a query which failed while updating or downgrading
+type QueryError struct {
+	Chain   string
+	Version Version
+	//SQL the failed statement. Empty if the transaction failed
+	SQL string
+	Err error
+}
+
+func (err *QueryError) Error() string {
+	if len(err.SQL) == 0 {
+		return fmt.Sprintf("chain '%s' v.%s: %s", err.Chain, err.Version, err.Err)
+	}
+	return fmt.Sprintf("chain '%s' v.%s \"%s\": %s", err.Chain, err.Version, err.SQL, err.Err)
+}
+
+//Unwrap returns the error of the database
+func (err *QueryError) Unwrap() error {
+	return err.Err
+}
+
+//UpdateError all queries which failed during RunUpdate
+type UpdateError struct {
+	Errors []*QueryError
+}
+
+func (err *UpdateError) Error() string {
+	messages := make([]string, len(err.Errors))
+	for i, queryErr := range err.Errors {
+		messages[i] = queryErr.Error()
+	}
+	return fmt.Sprintf("%d queries failed: %s", len(err.Errors), strings.Join(messages, "; "))
+}
+
+//Unwrap returns the QueryErrors to be used with errors.Is and errors.As
+func (err *UpdateError) Unwrap() []error {
+	errs := make([]error, len(err.Errors))
+	for i, queryErr := range err.Errors {
+		errs[i] = queryErr
+	}
+	return errs
+}
+
+//LegacyVersionError versions of a chain which were ordered differently as floats by older
+//releases. The version stored by an older release can't be converted safely. Change the
+//versions to keep their order, eg. use "0.30" instead of "0.3" next to "0.21"
+type LegacyVersionError struct {
+	Chain string
+	//Older was older than Newer as float
+	Older Version
+	Newer Version
+}
+
+func (err *LegacyVersionError) Error() string {
+	return fmt.Sprintf("%s: chain '%s' v.%s has to be older than v.%s", ErrLegacyVersionOrder, err.Chain, err.Older, err.Newer)
+}
+
+//Unwrap returns ErrLegacyVersionOrder
+func (err *LegacyVersionError) Unwrap() error {
+	return ErrLegacyVersionOrder
+}
+
+//ChainError a problem of a QueryChain found by Validate
+type ChainError struct {
+	Chain string
+	//Query the index of the query in QueryChain.Queries, -1 if the chain itself is invalid
+	Query   int
+	Version Version
+	Err     error
+}
+
+func (err *ChainError) Error() string {
+	if err.Query < 0 {
+		if len(err.Chain) == 0 {
+			return err.Err.Error()
+		}
+		return fmt.Sprintf("chain '%s': %s", err.Chain, err.Err)
+	}
+	return fmt.Sprintf("chain '%s' queries[%d] v.%s: %s", err.Chain, err.Query, err.Version, err.Err)
+}
+
+//Unwrap returns the cause of the problem
+func (err *ChainError) Unwrap() error {
+	return err.Err
+}
+
+//ValidationError all problems found by Validate
+type ValidationError struct {
+	Errors []*ChainError
+}
+
+func (err *ValidationError) Error() string {
+	messages := make([]string, len(err.Errors))
+	for i, chainErr := range err.Errors {
+		messages[i] = chainErr.Error()
+	}
+	return fmt.Sprintf("%d invalid query chain definitions: %s", len(err.Errors), strings.Join(messages, "; "))
+}
+
+//Unwrap returns the ChainErrors to be used with errors.Is and errors.As
+func (err *ValidationError) Unwrap() []error {
+	errs := make([]error, len(err.Errors))
+	for i, chainErr := range err.Errors {
+		errs[i] = chainErr
+	}
+	return errs
+}
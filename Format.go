@@ -0,0 +1,106 @@
+package godbhelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+//formatOf returns the explicitly passed format or detects it by the extension of file
+func formatOf(file string, format []ExportFormat) ExportFormat {
+	if len(format) > 0 && format[0] != FormatAuto {
+		return format[0]
+	}
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatJSON
+}
+
+//encodeQueryChain writes queryChain to w using format
+func encodeQueryChain(w io.Writer, queryChain *QueryChain, format ExportFormat) error {
+	switch format {
+	case FormatAuto, FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(queryChain)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(queryChain); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case FormatTOML:
+		tagged, err := queryChain.mapParams(tomlParams)
+		if err != nil {
+			return err
+		}
+		return toml.NewEncoder(w).Encode(tagged)
+	}
+	return fmt.Errorf("%w: %d", ErrInvalidExportFormat, format)
+}
+
+//decodeQueryChain reads a QueryChain from r using format
+func decodeQueryChain(r io.Reader, format ExportFormat) (*QueryChain, error) {
+	var chain QueryChain
+	var err error
+
+	switch format {
+	case FormatAuto, FormatJSON:
+		err = json.NewDecoder(r).Decode(&chain)
+	case FormatYAML:
+		err = yaml.NewDecoder(r).Decode(&chain)
+	case FormatTOML:
+		if err = toml.NewDecoder(r).Decode(&chain); err == nil {
+			return chain.mapParams(tomlUntagParams)
+		}
+	default:
+		err = fmt.Errorf("%w: %d", ErrInvalidExportFormat, format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &chain, nil
+}
+
+//MarshalYAML writes the statements of query as literal block scalars
+func (query SQLQuery) MarshalYAML() (interface{}, error) {
+	//Without the methods of SQLQuery to not call MarshalYAML again
+	type plainQuery SQLQuery
+
+	var node yaml.Node
+	if err := node.Encode(plainQuery(query)); err != nil {
+		return nil, err
+	}
+
+	literalSQL(&node)
+	return &node, nil
+}
+
+//literalSQL sets the style of the statements of an encoded SQLQuery
+//and of its variants to literal
+func literalSQL(node *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		value := node.Content[i+1]
+		switch node.Content[i].Value {
+		case "query", "queryf", "down", "downf":
+			value.Style = yaml.LiteralStyle
+		case "variants":
+			for j := 1; j < len(value.Content); j += 2 {
+				literalSQL(value.Content[j])
+			}
+		}
+	}
+}
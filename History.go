@@ -0,0 +1,192 @@
+package godbhelper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//Kinds of MigrationRecords
+const (
+	//MigrationUp a query run by RunUpdate
+	MigrationUp = "up"
+	//MigrationDown a down statement run by RunDowngrade
+	MigrationDown = "down"
+	//MigrationBaseline a version set by Baseline without running queries
+	MigrationBaseline = "baseline"
+	//MigrationRepeatable a repeatable query run by RunUpdate
+	MigrationRepeatable = "repeatable"
+)
+
+//MigrationRecord an executed query of the migration history
+type MigrationRecord struct {
+	ID    int64
+	Chain string
+	//Version the VersionAdded of the query
+	Version Version
+	//Index the position of the query within the queries of its version
+	Index int
+	//Kind MigrationUp, MigrationDown, MigrationBaseline or MigrationRepeatable
+	Kind string
+	//Checksum sha256 of the statement
+	Checksum  string
+	Statement string
+	AppliedAt time.Time
+	Duration  time.Duration
+	Success   bool
+	//Error the error message if the query failed
+	Error string
+}
+
+//migrationRow a MigrationRecord in the history table
+type migrationRow struct {
+	ID           int64   `db:"id"`
+	Chain        string  `db:"chain"`
+	Version      Version `db:"version"`
+	QueryIndex   int     `db:"query_index"`
+	Kind         string  `db:"kind"`
+	Checksum     string  `db:"checksum"`
+	Statement    string  `db:"statement"`
+	AppliedAt    dbTime  `db:"applied_at"`
+	DurationNs   int64   `db:"duration_ns"`
+	Success      bool    `db:"success"`
+	ErrorMessage string  `db:"error_message"`
+}
+
+//initMigrationHistory creates the history table
+func (dbhelper *DBhelper) initMigrationHistory() error {
+	var id, timestamp string
+	switch dbhelper.dbKind {
+	case Sqlite, SqliteEncrypted:
+		id, timestamp = "INTEGER PRIMARY KEY AUTOINCREMENT", "TIMESTAMP"
+	case Mysql:
+		id, timestamp = "BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY", "DATETIME(6)"
+	case Postgres:
+		id, timestamp = "BIGSERIAL PRIMARY KEY", "TIMESTAMP"
+	default:
+		return ErrDBNotSupported
+	}
+
+	_, err := dbhelper.DB.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+		"id %s, "+
+		"chain VARCHAR(255) NOT NULL, "+
+		"version VARCHAR(64) NOT NULL, "+
+		"query_index INT NOT NULL, "+
+		"kind VARCHAR(16) NOT NULL, "+
+		"checksum CHAR(64) NOT NULL, "+
+		"statement TEXT NOT NULL, "+
+		"applied_at %s NOT NULL, "+
+		"duration_ns BIGINT NOT NULL, "+
+		"success %s NOT NULL, "+
+		"error_message TEXT NOT NULL)",
+		TableMigrationHistory, id, timestamp, boolValue(dbhelper.dbKind)))
+	return err
+}
+
+//addMigrationRecord writes record into the history table using execer
+func (dbhelper *DBhelper) addMigrationRecord(execer sqlx.Execer, record MigrationRecord) error {
+	_, err := execer.Exec(dbhelper.DB.Rebind(fmt.Sprintf("INSERT INTO %s "+
+		"(chain, version, query_index, kind, checksum, statement, applied_at, duration_ns, success, error_message) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", TableMigrationHistory)),
+		record.Chain, record.Version, record.Index, record.Kind, record.Checksum, record.Statement,
+		record.AppliedAt.UTC(), record.Duration.Nanoseconds(), record.Success, record.Error)
+	return err
+}
+
+//MigrationHistory returns every query executed by RunUpdate and RunDowngrade, oldest first.
+//Pass chain names to return the records of these chains only
+func (dbhelper *DBhelper) MigrationHistory(chains ...string) ([]MigrationRecord, error) {
+	query := "SELECT * FROM " + TableMigrationHistory
+	var args []interface{}
+	if len(chains) > 0 {
+		query += " WHERE chain IN (?" + strings.Repeat(", ?", len(chains)-1) + ")"
+		args = stringArrToInterface(chains)
+	}
+	query = dbhelper.DB.Rebind(query + " ORDER BY id")
+
+	//The table gets created by the first update
+	exists, err := dbhelper.tableExists(TableMigrationHistory)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	var rows []migrationRow
+	if err := dbhelper.DB.Select(&rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	records := make([]MigrationRecord, len(rows))
+	for i, row := range rows {
+		records[i] = MigrationRecord{
+			ID:        row.ID,
+			Chain:     row.Chain,
+			Version:   row.Version,
+			Index:     row.QueryIndex,
+			Kind:      row.Kind,
+			Checksum:  row.Checksum,
+			Statement: row.Statement,
+			AppliedAt: time.Time(row.AppliedAt),
+			Duration:  time.Duration(row.DurationNs),
+			Success:   row.Success,
+			Error:     row.ErrorMessage,
+		}
+	}
+
+	return records, nil
+}
+
+//checksum returns the hex encoded sha256 of statement
+func checksum(statement string) string {
+	sum := sha256.Sum256([]byte(statement))
+	return hex.EncodeToString(sum[:])
+}
+
+//dbTime scans timestamps of all supported databases. Mysql returns
+//text if the connection wasn't opened with parseTime=True
+type dbTime time.Time
+
+//Scan implements sql.Scanner
+func (t *dbTime) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case time.Time:
+		*t = dbTime(v)
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	case int64:
+		*t = dbTime(time.Unix(v, 0))
+		return nil
+	case nil:
+		*t = dbTime(time.Time{})
+		return nil
+	}
+	return fmt.Errorf("can't scan %T into time", value)
+}
+
+func (t *dbTime) parse(s string) error {
+	for _, layout := range []string{
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05.999999999",
+		time.RFC3339Nano,
+	} {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			*t = dbTime(parsed)
+			return nil
+		}
+	}
+
+	//Unix timestamp
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*t = dbTime(time.Unix(n, 0))
+		return nil
+	}
+
+	return fmt.Errorf("can't parse time '%s'", s)
+}
@@ -0,0 +1,115 @@
+package godbhelper
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//HookPoint specifies when a migration hook runs
+type HookPoint uint8
+
+const (
+	//HookBeforeUpdate runs before RunUpdate or RunDowngrade executes the first query
+	HookBeforeUpdate HookPoint = iota
+	//HookAfterUpdate runs after RunUpdate or RunDowngrade, also if the update failed
+	HookAfterUpdate
+	//HookBeforeChain runs before the versioned queries of a chain. Repeatable
+	//queries run after all chains and aren't surrounded by chain hooks.
+	//RunDowngrade runs it before undoing the versions of a chain
+	HookBeforeChain
+	//HookAfterChain runs after the versioned queries of a chain, also if one failed.
+	//RunDowngrade runs it after undoing the versions of a chain
+	HookAfterChain
+	//HookBeforeQuery runs in the transaction of a query before executing it
+	HookBeforeQuery
+	//HookAfterQuery runs in the transaction of a query after it succeeded
+	HookAfterQuery
+)
+
+//String returns the name of point
+func (point HookPoint) String() string {
+	switch point {
+	case HookBeforeUpdate:
+		return "before update"
+	case HookAfterUpdate:
+		return "after update"
+	case HookBeforeChain:
+		return "before chain"
+	case HookAfterChain:
+		return "after chain"
+	case HookBeforeQuery:
+		return "before query"
+	case HookAfterQuery:
+		return "after query"
+	}
+	return fmt.Sprintf("hook %d", point)
+}
+
+//HookEvent the state of the update passed to a migration hook
+type HookEvent struct {
+	//Chain the name of the chain. Empty for update hooks
+	Chain string
+	//Version the version of the query, the version of the chain
+	//for chain hooks or the CurrentVersion for update hooks
+	Version Version
+	//SQL the statement with formatted Fparams. Query hooks only
+	SQL    string
+	Params QueryParams
+	//Down true while running RunDowngrade
+	Down bool
+	//Tx the transaction of the query. Query hooks only
+	Tx *sqlx.Tx
+	//Err the error of the chain or the update. After hooks only
+	Err error
+}
+
+//HookFunc a migration hook. Returning an error aborts the update and
+//rolls back the transaction of the current query
+type HookFunc func(dbhelper *DBhelper, event HookEvent) error
+
+//HookError the error returned by a migration hook
+type HookError struct {
+	Point   HookPoint
+	Chain   string
+	Version Version
+	Err     error
+}
+
+func (err *HookError) Error() string {
+	if len(err.Chain) > 0 {
+		return fmt.Sprintf("%s hook aborted update of chain '%s' v.%s: %s", err.Point, err.Chain, err.Version, err.Err)
+	}
+	return fmt.Sprintf("%s hook aborted update: %s", err.Point, err.Err)
+}
+
+//Unwrap returns the error of the hook
+func (err *HookError) Unwrap() error {
+	return err.Err
+}
+
+//AddHook adds a migration hook which runs at point. Hooks of
+//the same point run in the order they were added
+func (dbhelper *DBhelper) AddHook(point HookPoint, hook HookFunc) {
+	if dbhelper.hooks == nil {
+		dbhelper.hooks = make(map[HookPoint][]HookFunc)
+	}
+	dbhelper.hooks[point] = append(dbhelper.hooks[point], hook)
+}
+
+//runHooks runs the hooks of point and returns the first error as HookError
+func (dbhelper *DBhelper) runHooks(point HookPoint, event HookEvent) error {
+	for _, hook := range dbhelper.hooks[point] {
+		if err := hook(dbhelper, event); err != nil {
+			hookErr := &HookError{
+				Point:   point,
+				Chain:   event.Chain,
+				Version: event.Version,
+				Err:     err,
+			}
+			dbhelper.handleErrHook(hookErr, point.String()+" hook")
+			return hookErr
+		}
+	}
+	return nil
+}
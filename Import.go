@@ -0,0 +1,349 @@
+package godbhelper
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//Default names of the version tables of golang-migrate and goose
+const (
+	//GolangMigrateTable the version table of golang-migrate
+	GolangMigrateTable = "schema_migrations"
+	//GooseTable the version table of goose
+	GooseTable = "goose_db_version"
+)
+
+//golangMigrateFileRegex matches golang-migrate files like 0001_add_users.up.sql
+var golangMigrateFileRegex = regexp.MustCompile(`^([0-9]+)_(.+)\.(up|down)\.sql$`)
+
+//gooseFileRegex matches goose files like 20200101120000_add_users.sql
+var gooseFileRegex = regexp.MustCompile(`^([0-9]+)_(.+)\.sql$`)
+
+//LoadGolangMigrateDir loads the .sql files of a golang-migrate directory into a QueryChain.
+//Files are named like 0001_add_users.up.sql and 0001_add_users.down.sql.
+//The number of a file is its version. Pass dbKind to split the files like
+//SplitStatements does for the database system
+func LoadGolangMigrateDir(name, dir string, chainOrder int, dbKind ...dbsys) (*QueryChain, error) {
+	return LoadGolangMigrateDirFS(os.DirFS(dir), name, ".", chainOrder, dbKind...)
+}
+
+//LoadGolangMigrateDirFS like LoadGolangMigrateDir but reads dir from fsys (eg. an embed.FS)
+func LoadGolangMigrateDirFS(fsys fs.FS, name, dir string, chainOrder int, dbKind ...dbsys) (*QueryChain, error) {
+	files, err := readMigrationDir(fsys, dir, parseGolangMigrateName)
+	if err != nil {
+		return nil, err
+	}
+
+	queryChain := QueryChain{
+		Name:  name,
+		Order: chainOrder,
+	}
+
+	for _, file := range files {
+		queries, err := loadMigrationFile(fsys, dir, file, dbKind...)
+		if err != nil {
+			return nil, err
+		}
+		queryChain.Queries = append(queryChain.Queries, queries...)
+	}
+
+	return &queryChain, nil
+}
+
+//LoadGooseDir loads the .sql files of a goose directory into a QueryChain.
+//Files are named like 20200101120000_add_users.sql and contain '-- +goose Up'
+//and '-- +goose Down' sections. The number of a file is its version.
+//Go migrations of goose aren't loaded, add them as SQLQuery.Func.
+//Pass dbKind to split the files like SplitStatements does for the database system
+func LoadGooseDir(name, dir string, chainOrder int, dbKind ...dbsys) (*QueryChain, error) {
+	return LoadGooseDirFS(os.DirFS(dir), name, ".", chainOrder, dbKind...)
+}
+
+//LoadGooseDirFS like LoadGooseDir but reads dir from fsys (eg. an embed.FS)
+func LoadGooseDirFS(fsys fs.FS, name, dir string, chainOrder int, dbKind ...dbsys) (*QueryChain, error) {
+	files, err := readMigrationDir(fsys, dir, parseGooseName)
+	if err != nil {
+		return nil, err
+	}
+
+	queryChain := QueryChain{
+		Name:  name,
+		Order: chainOrder,
+	}
+
+	for _, file := range files {
+		up, down, err := readGooseFile(fsys, path.Join(dir, file.up), dbKind...)
+		if err != nil {
+			return nil, err
+		}
+		queryChain.Queries = append(queryChain.Queries, migrationQueries(file.version, up, down)...)
+	}
+
+	return &queryChain, nil
+}
+
+//LoadGolangMigrateDir loads a golang-migrate directory into a QueryChain and adds it.
+//See LoadGolangMigrateDir for the names of the files
+func (dbhelper *DBhelper) LoadGolangMigrateDir(name, dir string, chainOrder int) error {
+	queries, err := LoadGolangMigrateDir(name, dir, chainOrder, dbhelper.dbKind)
+	if err != nil {
+		return dbhelper.handleErrHook(err, "loading migrations: "+name)
+	}
+	dbhelper.AddQueryChain(*queries)
+	return nil
+}
+
+//LoadGooseDir loads a goose directory into a QueryChain and adds it.
+//See LoadGooseDir for the content of the files
+func (dbhelper *DBhelper) LoadGooseDir(name, dir string, chainOrder int) error {
+	queries, err := LoadGooseDir(name, dir, chainOrder, dbhelper.dbKind)
+	if err != nil {
+		return dbhelper.handleErrHook(err, "loading migrations: "+name)
+	}
+	dbhelper.AddQueryChain(*queries)
+	return nil
+}
+
+//parseGolangMigrateName parses names like 0001_add_users.up.sql
+func parseGolangMigrateName(file string) (version, name string, down, ok bool) {
+	match := golangMigrateFileRegex.FindStringSubmatch(file)
+	if match == nil {
+		return "", "", false, false
+	}
+	return trimVersionNumber(match[1]), match[2], match[3] == "down", true
+}
+
+//parseGooseName parses names like 20200101120000_add_users.sql
+func parseGooseName(file string) (version, name string, down, ok bool) {
+	match := gooseFileRegex.FindStringSubmatch(file)
+	if match == nil {
+		return "", "", false, false
+	}
+	return trimVersionNumber(match[1]), match[2], false, true
+}
+
+//trimVersionNumber removes the leading zeros of a version number like 0001
+func trimVersionNumber(number string) string {
+	trimmed := strings.TrimLeft(number, "0")
+	if len(trimmed) == 0 {
+		return "0"
+	}
+	return trimmed
+}
+
+//readGooseFile reads the statements of the up and down sections of a goose file.
+//Statements are split like SplitStatements does, except the ones between
+//'-- +goose StatementBegin' and '-- +goose StatementEnd'
+func readGooseFile(fsys fs.FS, file string, dbKind ...dbsys) (up, down []Statement, err error) {
+	f, err := fsys.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var section *[]Statement
+	var chunk, block strings.Builder
+	var chunkLine, blockLine int
+	inBlock := false
+
+	//flush splits the collected lines into the statements of the current section.
+	//Only comments are allowed before the first section
+	flush := func() error {
+		statements, err := SplitStatements(strings.NewReader(chunk.String()), dbKind...)
+		if err != nil {
+			return err
+		}
+		if section == nil && len(statements) > 0 {
+			return fmt.Errorf("%w: line %d: statement outside of '-- +goose Up' or '-- +goose Down'", ErrInvalidMigrationFile, statements[0].Line+chunkLine-1)
+		}
+
+		for _, statement := range statements {
+			statement.Line += chunkLine - 1
+			*section = append(*section, statement)
+		}
+		chunk.Reset()
+		return nil
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+
+		annotation, isAnnotation := gooseAnnotation(text)
+		if !isAnnotation {
+			if inBlock {
+				block.WriteString(text + "\n")
+			} else {
+				if chunk.Len() == 0 {
+					chunkLine = line
+				}
+				chunk.WriteString(text + "\n")
+			}
+			continue
+		}
+
+		switch annotation {
+		case "up", "down":
+			if inBlock {
+				return nil, nil, fmt.Errorf("%w: %s: line %d: missing StatementEnd", ErrInvalidMigrationFile, file, blockLine)
+			}
+			if err = flush(); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", file, err)
+			}
+
+			section = &up
+			if annotation == "down" {
+				section = &down
+			}
+		case "statementbegin":
+			if err = flush(); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", file, err)
+			}
+			inBlock = true
+			blockLine = line + 1
+		case "statementend":
+			if !inBlock {
+				return nil, nil, fmt.Errorf("%w: %s: line %d: StatementEnd without StatementBegin", ErrInvalidMigrationFile, file, line)
+			}
+			inBlock = false
+
+			if sql := strings.TrimSpace(block.String()); len(sql) > 0 {
+				if section == nil {
+					return nil, nil, fmt.Errorf("%w: %s: line %d: statement outside of '-- +goose Up' or '-- +goose Down'", ErrInvalidMigrationFile, file, blockLine)
+				}
+				*section = append(*section, Statement{SQL: sql, Line: blockLine})
+			}
+			block.Reset()
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if inBlock {
+		return nil, nil, fmt.Errorf("%w: %s: line %d: missing StatementEnd", ErrInvalidMigrationFile, file, blockLine)
+	}
+	if err = flush(); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return up, down, nil
+}
+
+//gooseAnnotation returns the lowercase annotation of a line like '-- +goose Up'
+func gooseAnnotation(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "--") {
+		return "", false
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(line, "--"))
+	if len(fields) < 2 || fields[0] != "+goose" {
+		return "", false
+	}
+	return strings.ToLower(fields[1]), true
+}
+
+//TakeOverGolangMigrate stores the version of the golang-migrate version table as version
+//of chain, so RunUpdate continues where golang-migrate stopped. Nothing happens if the
+//table doesn't exist or the chain already has a version. The table isn't changed.
+//Pass a table name if golang-migrate used another one than GolangMigrateTable
+func (dbhelper *DBhelper) TakeOverGolangMigrate(chain string, table ...string) error {
+	name := GolangMigrateTable
+	if len(table) > 0 {
+		name = table[0]
+	}
+
+	exists, err := dbhelper.tableExists(name)
+	if err != nil || !exists {
+		return err
+	}
+
+	var rows []struct {
+		Version int64 `db:"version"`
+		Dirty   bool  `db:"dirty"`
+	}
+	if err = dbhelper.QueryRows(&rows, "SELECT version, dirty FROM "+dbhelper.quoteIdentifier(name)); err != nil {
+		return err
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+	if len(rows) > 1 {
+		return ErrVersionStoreTooManyVersions
+	}
+	if rows[0].Dirty {
+		return fmt.Errorf("%w: %s v.%d", ErrDirtyMigration, name, rows[0].Version)
+	}
+
+	return dbhelper.takeOver(chain, rows[0].Version)
+}
+
+//TakeOverGoose stores the version of the goose version table as version of chain,
+//so RunUpdate continues where goose stopped. Nothing happens if the table doesn't
+//exist or the chain already has a version. The table isn't changed.
+//Pass a table name if goose used another one than GooseTable
+func (dbhelper *DBhelper) TakeOverGoose(chain string, table ...string) error {
+	name := GooseTable
+	if len(table) > 0 {
+		name = table[0]
+	}
+
+	exists, err := dbhelper.tableExists(name)
+	if err != nil || !exists {
+		return err
+	}
+
+	var rows []struct {
+		Version int64 `db:"version_id"`
+		Applied bool  `db:"is_applied"`
+	}
+	if err = dbhelper.QueryRows(&rows, "SELECT version_id, is_applied FROM "+dbhelper.quoteIdentifier(name)+" ORDER BY id DESC"); err != nil {
+		return err
+	}
+
+	//Like goose, the newest row of a version decides if it's applied
+	seen := make(map[int64]bool)
+	for _, row := range rows {
+		if seen[row.Version] {
+			continue
+		}
+		seen[row.Version] = true
+
+		if row.Applied {
+			return dbhelper.takeOver(chain, row.Version)
+		}
+	}
+
+	return nil
+}
+
+//takeOver baselines chain at version if it has no version yet
+func (dbhelper *DBhelper) takeOver(chain string, version int64) error {
+	//Version 0 is the initial version of goose
+	if version <= 0 {
+		return nil
+	}
+
+	err := dbhelper.BaselineChain(chain, Version(strconv.FormatInt(version, 10)))
+	if errors.Is(err, ErrAlreadyVersioned) {
+		return nil
+	}
+	return err
+}
+
+//tableExists returns true if table exists
+func (dbhelper *DBhelper) tableExists(table string) (bool, error) {
+	columns, err := dbhelper.columnTypes(table)
+	return len(columns) > 0, err
+}
@@ -0,0 +1,250 @@
+package godbhelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+//DefaultLockTimeout the time to wait for the migration lock if Options.LockTimeout isn't set
+const DefaultLockTimeout = time.Minute
+
+//DefaultLockStaleAfter the age of the Sqlite lock after which it's treated as left
+//by a crashed process if Options.LockStaleAfter isn't set
+const DefaultLockStaleAfter = time.Minute
+
+//postgresLockKey the key of the advisory lock used by Postgres
+const postgresLockKey int64 = 0x676f6462686c7072
+
+//mysqlLockName the name of the lock used by Mysql. Lock names are limited to
+//64 characters, so the name of the database (empty if none is selected) is hashed
+const mysqlLockName = "CONCAT('godbhelper.', SHA1(IFNULL(DATABASE(), '')))"
+
+//lockRetryInterval the time between two tries to acquire the lock
+const lockRetryInterval = 250 * time.Millisecond
+
+//LockError the migration lock couldn't be acquired
+type LockError struct {
+	Timeout time.Duration
+	//Err ErrLockTimeout or the error of the database
+	Err error
+}
+
+func (err *LockError) Error() string {
+	if errors.Is(err.Err, ErrLockTimeout) {
+		return fmt.Sprintf("Can't acquire migration lock within %s: %s", err.Timeout, err.Err)
+	}
+	return fmt.Sprintf("Can't acquire migration lock: %s", err.Err)
+}
+
+//Unwrap returns the cause of the LockError
+func (err *LockError) Unwrap() error {
+	return err.Err
+}
+
+//lockMigrations acquires the migration lock, so only one process updates the
+//database at a time. Postgres and Mysql use advisory locks which are released
+//if the connection gets closed. Sqlite uses the TableMigrationLock table.
+//Call the returned func to release the lock
+func (dbhelper *DBhelper) lockMigrations() (func(), error) {
+	timeout := dbhelper.Options.LockTimeout
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var unlock func()
+	var err error
+	switch dbhelper.dbKind {
+	case Postgres:
+		unlock, err = dbhelper.lockPostgres(ctx)
+	case Mysql:
+		unlock, err = dbhelper.lockMysql(ctx, timeout)
+	case Sqlite, SqliteEncrypted:
+		unlock, err = dbhelper.lockSqlite(ctx)
+	default:
+		err = ErrDBNotSupported
+	}
+
+	if err != nil {
+		//Keep errors of the database which happened before the timeout
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = ErrLockTimeout
+		}
+		return nil, &LockError{Timeout: timeout, Err: err}
+	}
+
+	return unlock, nil
+}
+
+//lockPostgres acquires a session level advisory lock on a dedicated connection
+func (dbhelper *DBhelper) lockPostgres(ctx context.Context) (func(), error) {
+	conn, err := dbhelper.DB.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		var locked bool
+		err = conn.GetContext(ctx, &locked, "SELECT pg_try_advisory_lock($1)", postgresLockKey)
+		if err == nil && locked {
+			break
+		}
+
+		if err == nil {
+			err = waitForRetry(ctx)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return func() {
+		conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", postgresLockKey)
+		conn.Close()
+	}, nil
+}
+
+//lockMysql acquires a named lock of the current database on a dedicated connection
+func (dbhelper *DBhelper) lockMysql(ctx context.Context, timeout time.Duration) (func(), error) {
+	conn, err := dbhelper.DB.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	//GET_LOCK returns NULL on errors
+	var locked *int
+	err = conn.GetContext(ctx, &locked, "SELECT GET_LOCK("+mysqlLockName+", ?)", int(math.Ceil(timeout.Seconds())))
+	if err == nil && (locked == nil || *locked != 1) {
+		err = ErrLockTimeout
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return func() {
+		conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK("+mysqlLockName+")")
+		conn.Close()
+	}, nil
+}
+
+//lockSqlite inserts the lock row into TableMigrationLock. The insert
+//fails as long as another process holds the lock. The holder refreshes
+//the row regularly, a row which wasn't refreshed within LockStaleAfter
+//was left by a crashed process and gets removed
+func (dbhelper *DBhelper) lockSqlite(ctx context.Context) (func(), error) {
+	_, err := dbhelper.DB.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY, owner TEXT NOT NULL, refreshed_at INTEGER NOT NULL)", TableMigrationLock))
+	if err != nil {
+		return nil, err
+	}
+
+	staleAfter := dbhelper.Options.LockStaleAfter
+	if staleAfter <= 0 {
+		staleAfter = DefaultLockStaleAfter
+	}
+
+	//The time makes owners of the same process unique
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano())
+
+	for {
+		_, err = dbhelper.DB.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (id, owner, refreshed_at) VALUES (1, ?, ?)", TableMigrationLock), owner, time.Now().UnixNano())
+		if err == nil {
+			break
+		}
+
+		//The insert got interrupted by the timeout
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		//Waiting doesn't help on other errors (eg. a read-only database)
+		if !sqliteLockHeld(err) {
+			return nil, err
+		}
+
+		//Remove the lock of a crashed process and try again right away
+		res, err := dbhelper.DB.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = 1 AND refreshed_at < ?", TableMigrationLock), time.Now().Add(-staleAfter).UnixNano())
+		if err == nil {
+			if removed, _ := res.RowsAffected(); removed > 0 {
+				continue
+			}
+		}
+
+		if err = waitForRetry(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	//Keep the lock fresh while updating
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(staleAfter / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				dbhelper.DB.Exec(fmt.Sprintf("UPDATE %s SET refreshed_at = ? WHERE id = 1 AND owner = ?", TableMigrationLock), time.Now().UnixNano(), owner)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		dbhelper.DB.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = 1 AND owner = ?", TableMigrationLock), owner)
+	}, nil
+}
+
+//sqliteLockHeld returns true if err of inserting the lock row means that another process
+//holds the lock. That's the case for violations of the primary key and busy or locked
+//databases. The messages of Sqlite are used, since the driver isn't imported
+func sqliteLockHeld(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, held := range []string{"unique constraint failed", "primary key must be unique", "database is locked", "table is locked"} {
+		if strings.Contains(msg, held) {
+			return true
+		}
+	}
+	return false
+}
+
+//ForceUnlockMigrations removes the migration lock of Sqlite which was
+//left by a crashed process without waiting for LockStaleAfter. Locks of
+//Postgres and Mysql are released automatically if their connection gets closed
+func (dbhelper *DBhelper) ForceUnlockMigrations() error {
+	if dbhelper.dbKind != Sqlite && dbhelper.dbKind != SqliteEncrypted {
+		return nil
+	}
+
+	columns, err := dbhelper.columnTypes(TableMigrationLock)
+	if err != nil || len(columns) == 0 {
+		return err
+	}
+
+	_, err = dbhelper.Exec("DELETE FROM " + TableMigrationLock)
+	return err
+}
+
+//waitForRetry waits lockRetryInterval or until ctx is done
+func waitForRetry(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(lockRetryInterval):
+		return nil
+	}
+}
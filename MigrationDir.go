@@ -0,0 +1,218 @@
+package godbhelper
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//migrationFileRegex matches migration files like V0.3__add_users.sql or V0.3__add_users.down.sql
+var migrationFileRegex = regexp.MustCompile(`^V([^_]+)__(.+?)(\.down)?\.sql$`)
+
+//migrationFile a versioned .sql file of a migration directory
+type migrationFile struct {
+	version Version
+	name    string
+	up      string
+	down    string
+}
+
+//migrationNameParser parses the name of a .sql file of a migration directory.
+//ok is false if the name doesn't match the layout of the directory
+type migrationNameParser func(file string) (version, name string, down, ok bool)
+
+//parseVersionedName parses names like V0.3__add_users.sql and V0.3__add_users.down.sql
+func parseVersionedName(file string) (version, name string, down, ok bool) {
+	match := migrationFileRegex.FindStringSubmatch(file)
+	if match == nil {
+		return "", "", false, false
+	}
+	return match[1], match[2], len(match[3]) > 0, true
+}
+
+//LoadMigrationDir loads the versioned .sql files of dir into a QueryChain.
+//Files have to be named like V0.3__add_users.sql. Every statement of a file gets
+//the version of the file. A file V0.3__add_users.down.sql contains the statements
+//undoing the version for RunDowngrade. Other files than .sql files are ignored.
+//Pass dbKind to split the files like SplitStatements does for the database system
+func LoadMigrationDir(name, dir string, chainOrder int, dbKind ...dbsys) (*QueryChain, error) {
+	return LoadMigrationDirFS(os.DirFS(dir), name, ".", chainOrder, dbKind...)
+}
+
+//LoadMigrationDirFS like LoadMigrationDir but reads dir from fsys (eg. an embed.FS)
+func LoadMigrationDirFS(fsys fs.FS, name, dir string, chainOrder int, dbKind ...dbsys) (*QueryChain, error) {
+	files, err := readMigrationDir(fsys, dir, parseVersionedName)
+	if err != nil {
+		return nil, err
+	}
+
+	queryChain := QueryChain{
+		Name:  name,
+		Order: chainOrder,
+	}
+
+	for _, file := range files {
+		queries, err := loadMigrationFile(fsys, dir, file, dbKind...)
+		if err != nil {
+			return nil, err
+		}
+		queryChain.Queries = append(queryChain.Queries, queries...)
+	}
+
+	return &queryChain, nil
+}
+
+//readMigrationDir returns the migration files of dir in fsys sorted by version
+func readMigrationDir(fsys fs.FS, dir string, parse migrationNameParser) ([]*migrationFile, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return parseMigrationFiles(names, parse)
+}
+
+//LoadMigrationDir loads the versioned .sql files of dir into a QueryChain and adds it.
+//See LoadMigrationDir for the names of the files
+func (dbhelper *DBhelper) LoadMigrationDir(name, dir string, chainOrder int) error {
+	queries, err := LoadMigrationDir(name, dir, chainOrder, dbhelper.dbKind)
+	if err != nil {
+		return dbhelper.handleErrHook(err, "loading migrations: "+name)
+	}
+	dbhelper.AddQueryChain(*queries)
+	return nil
+}
+
+//LoadMigrationDirFS like LoadMigrationDir but reads dir from fsys (eg. an embed.FS)
+func (dbhelper *DBhelper) LoadMigrationDirFS(fsys fs.FS, name, dir string, chainOrder int) error {
+	queries, err := LoadMigrationDirFS(fsys, name, dir, chainOrder, dbhelper.dbKind)
+	if err != nil {
+		return dbhelper.handleErrHook(err, "loading migrations: "+name)
+	}
+	dbhelper.AddQueryChain(*queries)
+	return nil
+}
+
+//parseMigrationFiles pairs the up and down files by their version
+//and returns them sorted by version
+func parseMigrationFiles(names []string, parse migrationNameParser) ([]*migrationFile, error) {
+	var files []*migrationFile
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+
+		rawVersion, title, isDown, ok := parse(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: '%s'", ErrInvalidMigrationFile, name)
+		}
+
+		version, err := ParseVersion(rawVersion)
+		if err != nil {
+			return nil, fmt.Errorf("%w: '%s': %s", ErrInvalidMigrationFile, name, err)
+		}
+
+		//Find the file of the version
+		var file *migrationFile
+		for _, f := range files {
+			if f.version.Compare(version) == 0 {
+				file = f
+				break
+			}
+		}
+
+		if file == nil {
+			file = &migrationFile{version: version, name: title}
+			files = append(files, file)
+		} else if file.name != title {
+			return nil, fmt.Errorf("%w: v.%s ('%s' and '%s')", ErrDuplicateMigrationVersion, version, file.name, title)
+		}
+
+		if (isDown && len(file.down) > 0) || (!isDown && len(file.up) > 0) {
+			return nil, fmt.Errorf("%w: v.%s ('%s')", ErrDuplicateMigrationVersion, version, name)
+		}
+
+		if isDown {
+			file.down = name
+		} else {
+			file.up = name
+		}
+	}
+
+	for _, file := range files {
+		if len(file.up) == 0 {
+			return nil, fmt.Errorf("%w: '%s' has no up file", ErrInvalidMigrationFile, file.down)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].version.Less(files[j].version)
+	})
+
+	return files, nil
+}
+
+//readStatementsFS reads the statements of file in fsys
+func readStatementsFS(fsys fs.FS, file string, dbKind ...dbsys) ([]Statement, error) {
+	f, err := fsys.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	statements, err := SplitStatements(f, dbKind...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return statements, nil
+}
+
+//loadMigrationFile creates the queries of file in dir of fsys
+func loadMigrationFile(fsys fs.FS, dir string, file *migrationFile, dbKind ...dbsys) ([]SQLQuery, error) {
+	up, err := readStatementsFS(fsys, path.Join(dir, file.up), dbKind...)
+	if err != nil {
+		return nil, err
+	}
+
+	var down []Statement
+	if len(file.down) > 0 {
+		if down, err = readStatementsFS(fsys, path.Join(dir, file.down), dbKind...); err != nil {
+			return nil, err
+		}
+	}
+
+	return migrationQueries(file.version, up, down), nil
+}
+
+//migrationQueries creates the queries of a version from its up and down statements
+func migrationQueries(version Version, up, down []Statement) []SQLQuery {
+	var queries []SQLQuery
+	for _, statement := range up {
+		queries = append(queries, SQLQuery{
+			VersionAdded: version,
+			QueryString:  statement.SQL,
+		})
+	}
+
+	//RunDowngrade undoes the queries of a version in reverse order.
+	//Append the down statements reversed to run them in file order
+	for i := len(down) - 1; i >= 0; i-- {
+		queries = append(queries, SQLQuery{
+			VersionAdded:    version,
+			DownQueryString: down[i].SQL,
+		})
+	}
+
+	return queries
+}
@@ -0,0 +1,203 @@
+package godbhelper
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//QueryParams the parameters of a SQLQuery. Strings, numbers, bools and nil keep
+//their type when a QueryChain gets exported and restored. []byte and time.Time are
+//exported as {"$bytes": "<base64>"} and {"$time": "<RFC 3339>"}. Restored integers
+//are int64, unsigned integers bigger than math.MaxInt64 uint64, other numbers float64
+type QueryParams []interface{}
+
+//Keys of the objects representing params without a type in the export formats
+const (
+	paramBytes = "$bytes"
+	paramTime  = "$time"
+	//paramNull is used by TOML only, which has no null
+	paramNull = "$null"
+	//paramUint is used by TOML only, which has no integers bigger than math.MaxInt64
+	paramUint = "$uint"
+)
+
+//tagged returns params with []byte, time.Time and for TOML nil and
+//big unsigned integers replaced by their tagged objects
+func (params QueryParams) tagged(toml bool) []interface{} {
+	if params == nil {
+		return nil
+	}
+
+	values := make([]interface{}, len(params))
+	for i, param := range params {
+		switch v := param.(type) {
+		case []byte:
+			values[i] = map[string]interface{}{paramBytes: base64.StdEncoding.EncodeToString(v)}
+		case time.Time:
+			values[i] = map[string]interface{}{paramTime: v.Format(time.RFC3339Nano)}
+		case nil:
+			if toml {
+				values[i] = map[string]interface{}{paramNull: true}
+			}
+		case uint64:
+			values[i] = param
+			if toml && v > math.MaxInt64 {
+				values[i] = map[string]interface{}{paramUint: strconv.FormatUint(v, 10)}
+			}
+		case uint:
+			values[i] = param
+			if toml && uint64(v) > math.MaxInt64 {
+				values[i] = map[string]interface{}{paramUint: strconv.FormatUint(uint64(v), 10)}
+			}
+		default:
+			values[i] = param
+		}
+	}
+	return values
+}
+
+//untagParams converts decoded values into QueryParams
+func untagParams(values []interface{}) (QueryParams, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	params := make(QueryParams, len(values))
+	for i, value := range values {
+		param, err := untagParam(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: param %d: %s", ErrInvalidQueryParam, i+1, err)
+		}
+		params[i] = param
+	}
+	return params, nil
+}
+
+//untagParam converts a decoded value into a param
+func untagParam(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, string, bool, int64, uint64, float64, []byte, time.Time:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return u, nil
+		}
+		return v.Float64()
+	case map[string]interface{}:
+		if len(v) != 1 {
+			break
+		}
+		if s, ok := v[paramBytes].(string); ok {
+			return base64.StdEncoding.DecodeString(s)
+		}
+		if s, ok := v[paramTime].(string); ok {
+			return time.Parse(time.RFC3339Nano, s)
+		}
+		if _, ok := v[paramNull]; ok {
+			return nil, nil
+		}
+		if s, ok := v[paramUint].(string); ok {
+			return strconv.ParseUint(s, 10, 64)
+		}
+	}
+	return nil, fmt.Errorf("unsupported value %v", value)
+}
+
+//MarshalJSON exports params with tagged []byte and time.Time values
+func (params QueryParams) MarshalJSON() ([]byte, error) {
+	return json.Marshal(params.tagged(false))
+}
+
+//UnmarshalJSON restores params exported by MarshalJSON. Strings of older releases are kept as strings
+func (params *QueryParams) UnmarshalJSON(b []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var values []interface{}
+	if err := decoder.Decode(&values); err != nil {
+		return err
+	}
+
+	restored, err := untagParams(values)
+	if err != nil {
+		return err
+	}
+	*params = restored
+	return nil
+}
+
+//MarshalYAML exports params with tagged []byte and time.Time values
+func (params QueryParams) MarshalYAML() (interface{}, error) {
+	return params.tagged(false), nil
+}
+
+//UnmarshalYAML restores params exported by MarshalYAML
+func (params *QueryParams) UnmarshalYAML(node *yaml.Node) error {
+	var values []interface{}
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+
+	restored, err := untagParams(values)
+	if err != nil {
+		return err
+	}
+	*params = restored
+	return nil
+}
+
+//mapParams returns a copy of queryChain with all params of its queries replaced by fn
+func (queryChain *QueryChain) mapParams(fn func(QueryParams) (QueryParams, error)) (*QueryChain, error) {
+	chain := *queryChain
+	chain.Queries = make([]SQLQuery, len(queryChain.Queries))
+
+	var err error
+	for i, query := range queryChain.Queries {
+		if query.Params, err = fn(query.Params); err != nil {
+			return nil, err
+		}
+		if query.DownParams, err = fn(query.DownParams); err != nil {
+			return nil, err
+		}
+
+		if query.Variants != nil {
+			variants := make(map[string]QueryVariant, len(query.Variants))
+			for name, variant := range query.Variants {
+				if variant.Params, err = fn(variant.Params); err != nil {
+					return nil, err
+				}
+				if variant.DownParams, err = fn(variant.DownParams); err != nil {
+					return nil, err
+				}
+				variants[name] = variant
+			}
+			query.Variants = variants
+		}
+
+		chain.Queries[i] = query
+	}
+
+	return &chain, nil
+}
+
+//tomlParams tags params for TOML which supports neither null, binary data nor big unsigned integers
+func tomlParams(params QueryParams) (QueryParams, error) {
+	return params.tagged(true), nil
+}
+
+//tomlUntagParams restores params exported by tomlParams
+func tomlUntagParams(params QueryParams) (QueryParams, error) {
+	return untagParams(params)
+}
@@ -0,0 +1,115 @@
+package godbhelper
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryParamsRoundTrip(t *testing.T) {
+	date := time.Date(2020, 1, 2, 3, 4, 5, 600, time.UTC)
+	tests := []struct {
+		name  string
+		param interface{}
+		want  interface{}
+	}{
+		{name: "string", param: "admin", want: "admin"},
+		{name: "empty string", param: "", want: ""},
+		{name: "numeric string", param: "1", want: "1"},
+		{name: "int", param: 29, want: int64(29)},
+		{name: "negative int", param: -1, want: int64(-1)},
+		{name: "min int64", param: int64(math.MinInt64), want: int64(math.MinInt64)},
+		{name: "max int64", param: int64(math.MaxInt64), want: int64(math.MaxInt64)},
+		{name: "small uint64", param: uint64(7), want: int64(7)},
+		{name: "max uint64", param: uint64(math.MaxUint64), want: uint64(math.MaxUint64)},
+		{name: "uint64 above max int64", param: uint64(math.MaxInt64) + 1, want: uint64(math.MaxInt64) + 1},
+		{name: "float", param: 1.5, want: 1.5},
+		{name: "bool", param: true, want: true},
+		{name: "nil", param: nil, want: nil},
+		{name: "bytes", param: []byte{0, 1, 255}, want: []byte{0, 1, 255}},
+		{name: "time", param: date, want: date},
+	}
+
+	formats := []struct {
+		name   string
+		format ExportFormat
+	}{
+		{name: "json", format: FormatJSON},
+		{name: "yaml", format: FormatYAML},
+		{name: "toml", format: FormatTOML},
+	}
+
+	for _, format := range formats {
+		for _, test := range tests {
+			t.Run(format.name+"/"+test.name, func(t *testing.T) {
+				chain := QueryChain{
+					Name: "chain",
+					Queries: []SQLQuery{{
+						VersionAdded: "1",
+						QueryString:  "INSERT INTO t VALUES (?, ?)",
+						Params:       QueryParams{test.param, "last"},
+					}},
+				}
+
+				var b bytes.Buffer
+				if err := chain.ExportQueryChainWriter(&b, format.format); err != nil {
+					t.Fatal(err)
+				}
+
+				restored, err := RestoreQueryChainReader(&b, format.format)
+				if err != nil {
+					t.Fatalf("%v\n%s", err, b.String())
+				}
+
+				want := QueryParams{test.want, "last"}
+				if got := restored.Queries[0].Params; !reflect.DeepEqual(got, want) {
+					t.Errorf("got %#v, want %#v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestQueryParamsUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want QueryParams
+		err  string
+	}{
+		{name: "null", src: `null`, want: nil},
+		{name: "empty", src: `[]`, want: QueryParams{}},
+		{name: "strings of older releases", src: `["1", "a"]`, want: QueryParams{"1", "a"}},
+		{name: "numbers", src: `[1, -1, 1.5, 1e3, 18446744073709551615]`, want: QueryParams{int64(1), int64(-1), 1.5, float64(1000), uint64(math.MaxUint64)}},
+		{name: "too big for uint64", src: `[18446744073709551616]`, want: QueryParams{float64(18446744073709551616)}},
+		{name: "tagged", src: `[{"$bytes": "AAE="}, {"$time": "2020-01-02T03:04:05Z"}]`, want: QueryParams{[]byte{0, 1}, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}},
+		{name: "unknown object", src: `[{"a": 1}]`, err: "param 1"},
+		{name: "two tags", src: `[1, {"$bytes": "", "$time": ""}]`, err: "param 2"},
+		{name: "invalid base64", src: `[{"$bytes": "!"}]`, err: "param 1"},
+		{name: "nested array", src: `[[1]]`, err: "param 1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var params QueryParams
+			err := params.UnmarshalJSON([]byte(test.src))
+			if len(test.err) > 0 {
+				if !errors.Is(err, ErrInvalidQueryParam) || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got %v, want %v containing %q", err, ErrInvalidQueryParam, test.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(params, test.want) {
+				t.Errorf("got %#v, want %#v", params, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package godbhelper
+
+//PlannedQuery a query which would be executed by RunUpdate
+type PlannedQuery struct {
+	Chain   string
+	Version Version
+	//SQL the statement with formatted Fparams
+	SQL    string
+	Params QueryParams
+	//Repeatable true if the query is a changed repeatable query
+	Repeatable bool
+}
+
+//Plan returns the queries RunUpdate would execute in the order they would run.
+//Nothing gets executed
+//Plan(fullUpdate bool)
+func (dbhelper *DBhelper) Plan(options ...bool) ([]PlannedQuery, error) {
+	var fullUpdate bool
+	if len(options) > 0 {
+		fullUpdate = options[0]
+	}
+
+	if err := dbhelper.sortQueryChains(); err != nil {
+		return nil, err
+	}
+
+	//The version of an older release has to be convertible
+	if _, err := dbhelper.legacyChainVersions(); err != nil {
+		return nil, err
+	}
+
+	repeatables, err := dbhelper.pendingRepeatables(fullUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []PlannedQuery
+	for _, chain := range dbhelper.QueryChains {
+		for _, query := range dbhelper.pendingQueries(chain, fullUpdate) {
+			plan = append(plan, PlannedQuery{
+				Chain:   chain.Name,
+				Version: query.VersionAdded,
+				SQL:     query.sql(),
+				Params:  query.Params,
+			})
+		}
+	}
+
+	//Repeatable queries run after all versioned ones
+	for _, chain := range dbhelper.QueryChains {
+		for _, queries := range repeatables[chain.Name] {
+			for _, query := range queries {
+				plan = append(plan, PlannedQuery{
+					Chain:      chain.Name,
+					Version:    query.VersionAdded,
+					SQL:        query.sql(),
+					Params:     query.Params,
+					Repeatable: true,
+				})
+			}
+		}
+	}
+
+	return plan, nil
+}
@@ -1,28 +1,71 @@
 package godbhelper
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
 )
 
-//QueryChain a list of SQL queries over time
+//QueryChain a list of SQL queries over time.
+//A chain runs after the chains named in DependsOn. Order decides
+//the order of chains which don't depend on each other
 type QueryChain struct {
-	Name    string     `json:"name"`
-	Order   int        `json:"order"`
-	Queries []SQLQuery `json:"queries"`
+	Name      string     `json:"name" yaml:"name" toml:"name"`
+	Order     int        `json:"order" yaml:"order" toml:"order"`
+	DependsOn []string   `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty" toml:"dependsOn,omitempty"`
+	Queries   []SQLQuery `json:"queries" yaml:"queries" toml:"queries"`
 }
 
 //SQLQuery a query
+//The Down statements undo the query when running RunDowngrade.
+//FdownQueryString gets formatted using Fparams.
+//Func runs Go code instead of a statement, DownFunc undoes it.
+//Both can't be exported.
+//Variants replace the statements on the database system of their key
+//("sqlite", "sqlite-encrypted", "mysql" or "postgres"). SqliteEncrypted
+//falls back to the "sqlite" variant.
+//Repeatable queries have no version. They run after all other queries
+//whenever they changed. Repeatable queries with the same Name run together
 type SQLQuery struct {
-	VersionAdded float32  `json:"vs"`
-	QueryString  string   `json:"query"`
-	Params       []string `json:"params"`
-	FqueryString string   `json:"queryf"`
-	Fparams      []string `json:"fparams"`
+	VersionAdded     Version                 `json:"vs" yaml:"vs" toml:"vs"`
+	QueryString      string                  `json:"query" yaml:"query,omitempty" toml:"query,multiline,omitempty"`
+	Params           QueryParams             `json:"params" yaml:"params,omitempty" toml:"params,omitempty"`
+	FqueryString     string                  `json:"queryf" yaml:"queryf,omitempty" toml:"queryf,multiline,omitempty"`
+	Fparams          []string                `json:"fparams" yaml:"fparams,omitempty" toml:"fparams,omitempty"`
+	DownQueryString  string                  `json:"down,omitempty" yaml:"down,omitempty" toml:"down,multiline,omitempty"`
+	DownParams       QueryParams             `json:"downparams,omitempty" yaml:"downparams,omitempty" toml:"downparams,omitempty"`
+	FdownQueryString string                  `json:"downf,omitempty" yaml:"downf,omitempty" toml:"downf,multiline,omitempty"`
+	Variants         map[string]QueryVariant `json:"variants,omitempty" yaml:"variants,omitempty" toml:"variants,omitempty"`
+	Repeatable       bool                    `json:"repeatable,omitempty" yaml:"repeatable,omitempty" toml:"repeatable,omitempty"`
+	Name             string                  `json:"name,omitempty" yaml:"name,omitempty" toml:"name,omitempty"`
+	Func             MigrationFunc           `json:"-" yaml:"-" toml:"-"`
+	DownFunc         MigrationFunc           `json:"-" yaml:"-" toml:"-"`
+}
+
+//QueryVariant the statements of a SQLQuery for one database system.
+//A variant replaces all statements of the query, empty ones aren't executed
+type QueryVariant struct {
+	QueryString      string      `json:"query,omitempty" yaml:"query,omitempty" toml:"query,multiline,omitempty"`
+	Params           QueryParams `json:"params,omitempty" yaml:"params,omitempty" toml:"params,omitempty"`
+	FqueryString     string      `json:"queryf,omitempty" yaml:"queryf,omitempty" toml:"queryf,multiline,omitempty"`
+	Fparams          []string    `json:"fparams,omitempty" yaml:"fparams,omitempty" toml:"fparams,omitempty"`
+	DownQueryString  string      `json:"down,omitempty" yaml:"down,omitempty" toml:"down,multiline,omitempty"`
+	DownParams       QueryParams `json:"downparams,omitempty" yaml:"downparams,omitempty" toml:"downparams,omitempty"`
+	FdownQueryString string      `json:"downf,omitempty" yaml:"downf,omitempty" toml:"downf,multiline,omitempty"`
 }
 
+//MigrationFunc a migration written in Go. tx is the transaction of the update step
+type MigrationFunc func(dbhelper *DBhelper, tx *sqlx.Tx) error
+
 //InitSQL init sql obj
 type InitSQL struct {
 	Query   string
@@ -30,6 +73,62 @@ type InitSQL struct {
 	FParams []string
 }
 
+//sql returns the statement of query. FqueryString gets formatted with Fparams.
+//Func is described by its name
+func (query SQLQuery) sql() string {
+	if query.Func != nil {
+		return "-- go: " + runtime.FuncForPC(reflect.ValueOf(query.Func).Pointer()).Name()
+	}
+
+	if len(query.FqueryString) > 0 {
+		return fmt.Sprintf(query.FqueryString, stringArrToInterface(query.Fparams)...)
+	}
+	return query.QueryString
+}
+
+//hasUp returns true if query has a statement or a Func
+func (query SQLQuery) hasUp() bool {
+	return len(query.QueryString)+len(query.FqueryString) > 0 || query.Func != nil
+}
+
+//hasDown returns true if query has a down statement or a DownFunc
+func (query SQLQuery) hasDown() bool {
+	return len(query.DownQueryString)+len(query.FdownQueryString) > 0 || query.DownFunc != nil
+}
+
+//forDialect returns query using the statements of the variant for kind
+func (query SQLQuery) forDialect(kind dbsys) SQLQuery {
+	variant, ok := query.Variants[dialectNames[kind]]
+	if !ok && kind == SqliteEncrypted {
+		variant, ok = query.Variants[dialectNames[Sqlite]]
+	}
+	if !ok {
+		return query
+	}
+
+	query.QueryString = variant.QueryString
+	query.Params = variant.Params
+	query.FqueryString = variant.FqueryString
+	query.Fparams = variant.Fparams
+	query.DownQueryString = variant.DownQueryString
+	query.DownParams = variant.DownParams
+	query.FdownQueryString = variant.FdownQueryString
+	query.Variants = nil
+	return query
+}
+
+//downQuery returns the down statement of query as SQLQuery
+func (query SQLQuery) downQuery() SQLQuery {
+	return SQLQuery{
+		VersionAdded: query.VersionAdded,
+		QueryString:  query.DownQueryString,
+		Params:       query.DownParams,
+		FqueryString: query.FdownQueryString,
+		Fparams:      query.Fparams,
+		Func:         query.DownFunc,
+	}
+}
+
 //NewQueryChain QueryChain constructor
 func NewQueryChain(name string, order int) *QueryChain {
 	return &QueryChain{
@@ -38,45 +137,132 @@ func NewQueryChain(name string, order int) *QueryChain {
 	}
 }
 
-//RestoreQueryChain loads an exported queryChain from file
-func RestoreQueryChain(file string) (*QueryChain, error) {
-	b, err := ioutil.ReadFile(file)
+//UnmarshalJSON reads a QueryChain. Older releases exported versions as floats.
+//Their fractions get padded to the same length (eg. 0.3 -> "0.30" next to 0.21),
+//so they keep the order they had as floats
+func (queryChain *QueryChain) UnmarshalJSON(b []byte) error {
+	type plainChain QueryChain
+	if err := json.Unmarshal(b, (*plainChain)(queryChain)); err != nil {
+		return err
+	}
+
+	var raw struct {
+		Queries []struct {
+			Version json.RawMessage `json:"vs"`
+		} `json:"queries"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	var floats []int
+	width := 0
+	for i, query := range raw.Queries {
+		version := strings.TrimSpace(string(query.Version))
+		if len(version) == 0 || version == "null" || version[0] == '"' {
+			continue
+		}
+
+		floats = append(floats, i)
+		if dot := strings.IndexByte(string(queryChain.Queries[i].VersionAdded), '.'); dot >= 0 {
+			if digits := len(queryChain.Queries[i].VersionAdded) - dot - 1; digits > width {
+				width = digits
+			}
+		}
+	}
+
+	for _, i := range floats {
+		queryChain.Queries[i].VersionAdded = queryChain.Queries[i].VersionAdded.padFraction(width)
+	}
+	return nil
+}
+
+//RestoreQueryChain loads an exported queryChain from file.
+//The format is detected by the extension (.json, .yaml/.yml, .toml) if not passed
+func RestoreQueryChain(file string, format ...ExportFormat) (*QueryChain, error) {
+	f, err := os.Open(file)
 	if err != nil {
 		return nil, err
 	}
-	var chain QueryChain
-	err = json.Unmarshal(b, &chain)
+	defer f.Close()
+	return RestoreQueryChainReader(f, formatOf(file, format))
+}
+
+//RestoreQueryChainFS loads an exported queryChain from a file of fsys (eg. an embed.FS)
+func RestoreQueryChainFS(fsys fs.FS, file string, format ...ExportFormat) (*QueryChain, error) {
+	f, err := fsys.Open(file)
 	if err != nil {
 		return nil, err
 	}
-	return &chain, nil
+	defer f.Close()
+	return RestoreQueryChainReader(f, formatOf(file, format))
 }
 
-//ExportQueryChain saves/exports a queryChain to a file
-func (queryChain *QueryChain) ExportQueryChain(file string, perm os.FileMode) error {
-	data, err := json.Marshal(queryChain)
-	if err != nil {
+//RestoreQueryChainReader loads an exported queryChain from r. JSON is used if no format is passed
+func RestoreQueryChainReader(r io.Reader, format ...ExportFormat) (*QueryChain, error) {
+	return decodeQueryChain(r, formatOf("", format))
+}
+
+//ExportQueryChain saves/exports a queryChain to a file.
+//The format is detected by the extension (.json, .yaml/.yml, .toml) if not passed
+func (queryChain *QueryChain) ExportQueryChain(file string, perm os.FileMode, format ...ExportFormat) error {
+	var buff bytes.Buffer
+	if err := encodeQueryChain(&buff, queryChain, formatOf(file, format)); err != nil {
 		return err
 	}
-	return ioutil.WriteFile(file, data, perm)
+	return ioutil.WriteFile(file, buff.Bytes(), perm)
 }
 
-//LoadQueries loads queries from a .sql file and executes the statements (row for row).
-//The SQLQuery Version of the statements are 0.
+//ExportQueryChainWriter exports a queryChain to w. JSON is used if no format is passed
+func (queryChain *QueryChain) ExportQueryChainWriter(w io.Writer, format ...ExportFormat) error {
+	return encodeQueryChain(w, queryChain, formatOf("", format))
+}
+
+//LoadQueries loads queries from a .sql file and executes the statements (see SplitStatements).
+//The SQLQuery Version of the statements are 0. Pass dbKind to split the file like
+//SplitStatements does for the database system.
 //This is intended to initialize the database-schema
-func LoadQueries(name, file string, chainOrder int) (*QueryChain, error) {
+func LoadQueries(name, file string, chainOrder int, dbKind ...dbsys) (*QueryChain, error) {
 	f, err := os.Open(file)
 	if err != nil {
 		return nil, err
 	}
-	fileScanner := bufio.NewScanner(f)
-	queryChain := QueryChain{}
+	defer f.Close()
+
+	queryChain, err := LoadQueriesReader(name, f, chainOrder, dbKind...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return queryChain, nil
+}
+
+//LoadQueriesFS like LoadQueries but reads file from fsys (eg. an embed.FS)
+func LoadQueriesFS(fsys fs.FS, name, file string, chainOrder int, dbKind ...dbsys) (*QueryChain, error) {
+	f, err := fsys.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	queryChain, err := LoadQueriesReader(name, f, chainOrder, dbKind...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return queryChain, nil
+}
+
+//LoadQueriesReader like LoadQueries but reads the .sql content from r
+func LoadQueriesReader(name string, r io.Reader, chainOrder int, dbKind ...dbsys) (*QueryChain, error) {
+	statements, err := SplitStatements(r, dbKind...)
+	if err != nil {
+		return nil, err
+	}
 
-	for fileScanner.Scan() {
-		sql := fileScanner.Text()
+	queryChain := QueryChain{}
+	for _, statement := range statements {
 		queryChain.Queries = append(queryChain.Queries, SQLQuery{
-			VersionAdded: 0,
-			QueryString:  sql,
+			VersionAdded: "0",
+			QueryString:  statement.SQL,
 		})
 	}
 	queryChain.Name = name
@@ -84,11 +270,21 @@ func LoadQueries(name, file string, chainOrder int) (*QueryChain, error) {
 	return &queryChain, nil
 }
 
-//LoadQueries loads queries from a .sql file and executes the statements (row for row).
+//LoadQueries loads queries from a .sql file and executes the statements (see SplitStatements).
 //The SQLQuery Version of the statements are 0.
 //This is intended to initialize the database-schema
 func (dbhelper *DBhelper) LoadQueries(name, file string, chainOrder int) error {
-	queries, err := LoadQueries(name, file, chainOrder)
+	queries, err := LoadQueries(name, file, chainOrder, dbhelper.dbKind)
+	if err != nil {
+		return dbhelper.handleErrHook(err, "loading Queries: "+name)
+	}
+	dbhelper.AddQueryChain(*queries)
+	return nil
+}
+
+//LoadQueriesFS like LoadQueries but reads file from fsys (eg. an embed.FS)
+func (dbhelper *DBhelper) LoadQueriesFS(fsys fs.FS, name, file string, chainOrder int) error {
+	queries, err := LoadQueriesFS(fsys, name, file, chainOrder, dbhelper.dbKind)
 	if err != nil {
 		return dbhelper.handleErrHook(err, "loading Queries: "+name)
 	}
@@ -102,7 +298,7 @@ func CreateInitVersionSQL(arg ...InitSQL) []SQLQuery {
 
 	for _, query := range arg {
 		queries = append(queries, SQLQuery{
-			VersionAdded: 0,
+			VersionAdded: "0",
 			Fparams:      query.FParams,
 			FqueryString: query.Query,
 			QueryString:  query.Query,
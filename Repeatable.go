@@ -0,0 +1,120 @@
+package godbhelper
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//repeatableKey identifies a repeatable query
+type repeatableKey struct {
+	chain string
+	name  string
+}
+
+//repeatableTableColumns the columns of the table containing the checksums of repeatable queries
+const repeatableTableColumns = "(chain VARCHAR(255) NOT NULL, name VARCHAR(255) NOT NULL, checksum VARCHAR(64) NOT NULL, PRIMARY KEY (chain, name))"
+
+//initRepeatables creates the table containing the checksums of the executed repeatable queries
+func (dbhelper *DBhelper) initRepeatables() error {
+	_, err := dbhelper.DB.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s %s", TableRepeatable, repeatableTableColumns))
+	return err
+}
+
+//pendingRepeatables returns the repeatable queries of every chain which changed
+//since they were executed the last time, grouped by their name. Queries with the
+//same name run together (eg. DROP VIEW and CREATE VIEW). fullUpdate returns all of them
+func (dbhelper *DBhelper) pendingRepeatables(fullUpdate bool) (map[string][][]SQLQuery, error) {
+	var rows []struct {
+		Chain    string `db:"chain"`
+		Name     string `db:"name"`
+		Checksum string `db:"checksum"`
+	}
+	//The table doesn't exist before the first update (see Plan)
+	exists, err := dbhelper.tableExists(TableRepeatable)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if err = dbhelper.DB.Select(&rows, "SELECT chain, name, checksum FROM "+TableRepeatable); err != nil {
+			return nil, err
+		}
+	}
+
+	applied := make(map[repeatableKey]string, len(rows))
+	for _, row := range rows {
+		applied[repeatableKey{row.Chain, row.Name}] = row.Checksum
+	}
+
+	pending := make(map[string][][]SQLQuery)
+	for _, chain := range dbhelper.QueryChains {
+		groups, err := repeatableGroups(chain.Name, dbhelper.dialectQueries(chain.Queries))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, group := range groups {
+			if fullUpdate || applied[repeatableKey{chain.Name, group[0].Name}] != repeatableChecksum(group) {
+				pending[chain.Name] = append(pending[chain.Name], group)
+			}
+		}
+	}
+
+	return pending, nil
+}
+
+//repeatableGroups groups the repeatable queries of a chain by their
+//name in the order of the first query of each name
+func repeatableGroups(chain string, queries []SQLQuery) ([][]SQLQuery, error) {
+	var groups [][]SQLQuery
+	index := make(map[string]int)
+	for _, query := range queries {
+		if !query.Repeatable || !query.hasUp() {
+			continue
+		}
+
+		if len(query.Name) == 0 {
+			return nil, fmt.Errorf("%w: chain '%s' \"%s\"", ErrUnnamedRepeatable, chain, query.sql())
+		}
+
+		if i, ok := index[query.Name]; ok {
+			groups[i] = append(groups[i], query)
+		} else {
+			index[query.Name] = len(groups)
+			groups = append(groups, []SQLQuery{query})
+		}
+	}
+	return groups, nil
+}
+
+//saveRepeatable stores the checksum of executed repeatable queries having the same name using execer
+func (dbhelper *DBhelper) saveRepeatable(execer sqlx.Execer, chain string, queries []SQLQuery) error {
+	name := queries[0].Name
+	if _, err := execer.Exec(dbhelper.DB.Rebind(fmt.Sprintf("DELETE FROM %s WHERE chain = ? AND name = ?", TableRepeatable)), chain, name); err != nil {
+		return err
+	}
+	_, err := execer.Exec(dbhelper.DB.Rebind(fmt.Sprintf("INSERT INTO %s (chain, name, checksum) VALUES (?, ?, ?)", TableRepeatable)), chain, name, repeatableChecksum(queries))
+	return err
+}
+
+//repeatableChecksum the checksum of the statements and the params of queries.
+//Repeatable queries run again if it changes
+func repeatableChecksum(queries []SQLQuery) string {
+	var content string
+	for _, query := range queries {
+		content += fmt.Sprintf("%s\n%v\n", query.sql(), query.Params)
+	}
+	return checksum(content)
+}
+
+//versionedQueries returns the queries of chain which aren't repeatable
+//using the variants for the database system of dbhelper
+func (dbhelper *DBhelper) versionedQueries(chain QueryChain) []SQLQuery {
+	var queries []SQLQuery
+	for _, query := range dbhelper.dialectQueries(chain.Queries) {
+		if !query.Repeatable {
+			queries = append(queries, query)
+		}
+	}
+	return queries
+}
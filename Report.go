@@ -0,0 +1,63 @@
+package godbhelper
+
+import "time"
+
+//QueryStatus the result of a query in an UpdateReport
+type QueryStatus string
+
+const (
+	//QuerySucceeded the query was executed and committed
+	QuerySucceeded QueryStatus = "succeeded"
+	//QueryFailed the query returned an error
+	QueryFailed QueryStatus = "failed"
+	//QueryRolledBack the query succeeded but another query of its transaction failed
+	QueryRolledBack QueryStatus = "rolledback"
+)
+
+//UpdateReport the result of RunUpdateWithReport
+type UpdateReport struct {
+	//OldVersion the newest version of all chains before the update
+	OldVersion Version `json:"oldVersion"`
+	//NewVersion the newest version of all chains after the update
+	NewVersion Version       `json:"newVersion"`
+	Chains     []ChainReport `json:"chains"`
+	Drifts     []*DriftError `json:"drifts,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	Succeeded  int           `json:"succeeded"`
+	Failed     int           `json:"failed"`
+	RolledBack int           `json:"rolledBack"`
+}
+
+//ChainReport the result of the update of a chain
+type ChainReport struct {
+	Name       string        `json:"name"`
+	OldVersion Version       `json:"oldVersion"`
+	NewVersion Version       `json:"newVersion"`
+	Queries    []QueryReport `json:"queries"`
+}
+
+//QueryReport the result of a query
+type QueryReport struct {
+	Version      Version       `json:"version"`
+	SQL          string        `json:"sql"`
+	Status       QueryStatus   `json:"status"`
+	Duration     time.Duration `json:"duration"`
+	RowsAffected int64         `json:"rowsAffected"`
+	Error        string        `json:"error,omitempty"`
+}
+
+//addChain adds chain to the report and counts its queries
+func (report *UpdateReport) addChain(chain ChainReport) {
+	for _, query := range chain.Queries {
+		switch query.Status {
+		case QuerySucceeded:
+			report.Succeeded++
+		case QueryFailed:
+			report.Failed++
+		case QueryRolledBack:
+			report.RolledBack++
+		}
+	}
+
+	report.Chains = append(report.Chains, chain)
+}
@@ -0,0 +1,300 @@
+package godbhelper
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+//Statement a statement of a .sql file
+type Statement struct {
+	SQL string
+	//Line the line the statement starts in
+	Line int
+}
+
+//SplitStatements splits the content of a .sql file into its statements.
+//Statements are separated by ';' which can be changed using MySQL 'DELIMITER' lines.
+//Separators in comments, string literals, quoted identifiers, dollar quoted
+//Postgres bodies and BEGIN...END blocks of triggers and procedures are ignored.
+//Empty statements and statements containing comments only are skipped.
+//Pass Mysql as dbKind to split MySQL files, which use backslash escapes
+//in string literals and '#' comments
+func SplitStatements(r io.Reader, dbKind ...dbsys) ([]Statement, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	splitter := sqlSplitter{
+		src:       string(b),
+		line:      1,
+		delimiter: ";",
+		mysql:     len(dbKind) > 0 && dbKind[0] == Mysql,
+	}
+	return splitter.split()
+}
+
+//sqlSplitter the state of SplitStatements
+type sqlSplitter struct {
+	src        string
+	pos        int
+	line       int
+	delimiter  string
+	statements []Statement
+	//mysql enables backslash escapes and '#' comments
+	mysql bool
+
+	//current statement
+	started   bool
+	start     int
+	startLine int
+	firstWord string
+	routine   bool
+	depth     int
+}
+
+func (splitter *sqlSplitter) split() ([]Statement, error) {
+	for splitter.pos < len(splitter.src) {
+		c := splitter.src[splitter.pos]
+		rest := splitter.src[splitter.pos:]
+
+		switch {
+		case c == '\n':
+			splitter.line++
+			splitter.pos++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			splitter.pos++
+		case strings.HasPrefix(rest, "--") || (c == '#' && splitter.mysql):
+			splitter.skipLine()
+		case strings.HasPrefix(rest, "/*"):
+			//Keep MySQL executable comments and optimizer hints
+			if strings.HasPrefix(rest, "/*!") || strings.HasPrefix(rest, "/*+") {
+				splitter.begin()
+			}
+			if err := splitter.skipBlock(2, "*/", "comment"); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(rest, splitter.delimiter) && (splitter.depth == 0 || splitter.delimiter != ";"):
+			splitter.end()
+			splitter.pos += len(splitter.delimiter)
+		case c == '\'' || c == '"' || c == '`':
+			splitter.begin()
+			if err := splitter.skipQuoted(c); err != nil {
+				return nil, err
+			}
+		case c == '$' && splitter.dollarTag() != "":
+			splitter.begin()
+			tag := splitter.dollarTag()
+			if err := splitter.skipBlock(len(tag), tag, "dollar quoted string"); err != nil {
+				return nil, err
+			}
+		case isWordChar(c):
+			if err := splitter.word(); err != nil {
+				return nil, err
+			}
+		default:
+			splitter.begin()
+			splitter.pos++
+		}
+	}
+
+	splitter.end()
+	return splitter.statements, nil
+}
+
+//begin starts a new statement at the current position if none is started
+func (splitter *sqlSplitter) begin() {
+	if splitter.started {
+		return
+	}
+
+	splitter.started = true
+	splitter.start = splitter.pos
+	splitter.startLine = splitter.line
+	splitter.firstWord = ""
+	splitter.routine = false
+	splitter.depth = 0
+}
+
+//end adds the current statement
+func (splitter *sqlSplitter) end() {
+	if !splitter.started {
+		return
+	}
+	splitter.started = false
+
+	sql := strings.TrimSpace(splitter.src[splitter.start:splitter.pos])
+	if len(sql) > 0 {
+		splitter.statements = append(splitter.statements, Statement{
+			SQL:  sql,
+			Line: splitter.startLine,
+		})
+	}
+}
+
+//word reads a keyword or an identifier and tracks BEGIN...END blocks
+//of triggers and procedures
+func (splitter *sqlSplitter) word() error {
+	startOfStatement := !splitter.started
+	splitter.begin()
+
+	word := strings.ToUpper(splitter.readWord())
+
+	//MySQL client command changing the delimiter
+	if startOfStatement && word == "DELIMITER" {
+		splitter.started = false
+		end := strings.IndexByte(splitter.src[splitter.pos:], '\n')
+		if end == -1 {
+			end = len(splitter.src) - splitter.pos
+		}
+
+		delimiter := strings.TrimSpace(splitter.src[splitter.pos : splitter.pos+end])
+		if len(delimiter) == 0 {
+			return splitter.error("missing delimiter")
+		}
+		splitter.delimiter = delimiter
+		splitter.pos += end
+		return nil
+	}
+
+	if len(splitter.firstWord) == 0 {
+		splitter.firstWord = word
+		return nil
+	}
+
+	switch word {
+	case "TRIGGER", "PROCEDURE", "FUNCTION", "EVENT":
+		if splitter.firstWord == "CREATE" && splitter.depth == 0 {
+			splitter.routine = true
+		}
+	case "BEGIN", "CASE":
+		if splitter.routine {
+			splitter.depth++
+		}
+	case "END":
+		if !splitter.routine {
+			break
+		}
+
+		//END IF, END LOOP... close blocks which weren't counted
+		pos := splitter.pos
+		for pos < len(splitter.src) && (splitter.src[pos] == ' ' || splitter.src[pos] == '\t') {
+			pos++
+		}
+		end := pos
+		for end < len(splitter.src) && isWordChar(splitter.src[end]) {
+			end++
+		}
+
+		switch strings.ToUpper(splitter.src[pos:end]) {
+		case "IF", "LOOP", "WHILE", "REPEAT":
+			splitter.pos = end
+		case "CASE":
+			splitter.pos = end
+			splitter.depth--
+		default:
+			splitter.depth--
+		}
+	}
+
+	return nil
+}
+
+//readWord reads a word at the current position
+func (splitter *sqlSplitter) readWord() string {
+	start := splitter.pos
+	for splitter.pos < len(splitter.src) && isWordChar(splitter.src[splitter.pos]) {
+		splitter.pos++
+	}
+	return splitter.src[start:splitter.pos]
+}
+
+//skipLine skips everything until the end of the current line
+func (splitter *sqlSplitter) skipLine() {
+	for splitter.pos < len(splitter.src) && splitter.src[splitter.pos] != '\n' {
+		splitter.pos++
+	}
+}
+
+//skipBlock skips the opening sequence of length open and everything until and including end
+func (splitter *sqlSplitter) skipBlock(open int, end, what string) error {
+	start := splitter.pos + open
+	i := strings.Index(splitter.src[start:], end)
+	if i == -1 {
+		return splitter.error("unterminated " + what)
+	}
+
+	splitter.line += strings.Count(splitter.src[splitter.pos:start+i], "\n")
+	splitter.pos = start + i + len(end)
+	return nil
+}
+
+//skipQuoted skips a string literal or a quoted identifier. Quotes are escaped
+//by doubling them. Backslashes escape characters in MySQL literals and Postgres E'' strings
+func (splitter *sqlSplitter) skipQuoted(quote byte) error {
+	line := splitter.line
+	escapes := quote == '\'' && splitter.pos > 0 && (splitter.src[splitter.pos-1] == 'E' || splitter.src[splitter.pos-1] == 'e') &&
+		(splitter.pos < 2 || !isWordChar(splitter.src[splitter.pos-2]))
+	if splitter.mysql && quote != '`' {
+		escapes = true
+	}
+
+	for splitter.pos++; splitter.pos < len(splitter.src); splitter.pos++ {
+		switch splitter.src[splitter.pos] {
+		case '\n':
+			splitter.line++
+		case '\\':
+			if escapes && splitter.pos+1 < len(splitter.src) {
+				splitter.pos++
+				if splitter.src[splitter.pos] == '\n' {
+					splitter.line++
+				}
+			}
+		case quote:
+			if splitter.pos+1 < len(splitter.src) && splitter.src[splitter.pos+1] == quote {
+				splitter.pos++
+				continue
+			}
+			splitter.pos++
+			return nil
+		}
+	}
+
+	splitter.line = line
+	return splitter.error(fmt.Sprintf("unterminated %c", quote))
+}
+
+//dollarTag returns the Postgres dollar quote tag like $$ or $body$
+//at the current position or an empty string
+func (splitter *sqlSplitter) dollarTag() string {
+	if splitter.pos > 0 && isWordChar(splitter.src[splitter.pos-1]) {
+		return ""
+	}
+
+	for i := splitter.pos + 1; i < len(splitter.src); i++ {
+		c := splitter.src[i]
+		switch {
+		case c == '$':
+			return splitter.src[splitter.pos : i+1]
+		case c >= '0' && c <= '9':
+			//Tags can't start with a digit ($1 is a parameter)
+			if i == splitter.pos+1 {
+				return ""
+			}
+		case !isWordChar(c):
+			return ""
+		}
+	}
+
+	return ""
+}
+
+func (splitter *sqlSplitter) error(msg string) error {
+	return fmt.Errorf("%w: line %d: %s", ErrInvalidSQLFile, splitter.line, msg)
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80
+}
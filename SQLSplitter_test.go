@@ -0,0 +1,216 @@
+package godbhelper
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		dbKind []dbsys
+		src    string
+		want   []Statement
+	}{
+		{
+			name: "single statement without delimiter",
+			src:  "SELECT 1",
+			want: []Statement{{SQL: "SELECT 1", Line: 1}},
+		},
+		{
+			name: "multi-line statements",
+			src:  "CREATE TABLE a (\n\tid int,\n\tname text\n);\n\nINSERT INTO a\nVALUES (1, 'x');",
+			want: []Statement{
+				{SQL: "CREATE TABLE a (\n\tid int,\n\tname text\n)", Line: 1},
+				{SQL: "INSERT INTO a\nVALUES (1, 'x')", Line: 6},
+			},
+		},
+		{
+			name: "empty statements",
+			src:  ";;SELECT 1;;\n;",
+			want: []Statement{{SQL: "SELECT 1", Line: 1}},
+		},
+		{
+			name: "line comments",
+			src:  "-- first; statement\nSELECT 1; -- trailing;\n-- only a comment;",
+			want: []Statement{{SQL: "SELECT 1", Line: 2}},
+		},
+		{
+			name: "block comments",
+			src:  "/* a;\nb; */ SELECT /* ; */ 1;\n/* only a comment; */",
+			want: []Statement{{SQL: "SELECT /* ; */ 1", Line: 2}},
+		},
+		{
+			name: "mysql executable comment",
+			src:  "/*!40101 SET NAMES utf8 */;\nSELECT 1;",
+			want: []Statement{
+				{SQL: "/*!40101 SET NAMES utf8 */", Line: 1},
+				{SQL: "SELECT 1", Line: 2},
+			},
+		},
+		{
+			name: "literals containing delimiters",
+			src:  "INSERT INTO t VALUES ('a;b', 'it''s; here', '--', '/*');\nSELECT 2;",
+			want: []Statement{
+				{SQL: "INSERT INTO t VALUES ('a;b', 'it''s; here', '--', '/*')", Line: 1},
+				{SQL: "SELECT 2", Line: 2},
+			},
+		},
+		{
+			name: "multi-line literal",
+			src:  "INSERT INTO t VALUES ('a\n;\nb');\nSELECT 2;",
+			want: []Statement{
+				{SQL: "INSERT INTO t VALUES ('a\n;\nb')", Line: 1},
+				{SQL: "SELECT 2", Line: 4},
+			},
+		},
+		{
+			name: "quoted identifiers",
+			src:  "SELECT \"a;b\", `c;d` FROM t;",
+			want: []Statement{{SQL: "SELECT \"a;b\", `c;d` FROM t", Line: 1}},
+		},
+		{
+			name: "backslash is no escape in standard literals",
+			src:  "INSERT INTO t VALUES ('C:\\');\nSELECT 2;",
+			want: []Statement{
+				{SQL: "INSERT INTO t VALUES ('C:\\')", Line: 1},
+				{SQL: "SELECT 2", Line: 2},
+			},
+		},
+		{
+			name: "postgres escape string",
+			src:  "INSERT INTO t VALUES (E'it\\'s; here');\nSELECT 2;",
+			want: []Statement{
+				{SQL: "INSERT INTO t VALUES (E'it\\'s; here')", Line: 1},
+				{SQL: "SELECT 2", Line: 2},
+			},
+		},
+		{
+			name: "dollar quoted body",
+			src:  "CREATE FUNCTION f() RETURNS int AS $$\nBEGIN\n\tRETURN 1;\nEND;\n$$ LANGUAGE plpgsql;\nSELECT f();",
+			want: []Statement{
+				{SQL: "CREATE FUNCTION f() RETURNS int AS $$\nBEGIN\n\tRETURN 1;\nEND;\n$$ LANGUAGE plpgsql", Line: 1},
+				{SQL: "SELECT f()", Line: 6},
+			},
+		},
+		{
+			name: "tagged dollar quoted body",
+			src:  "DO $body$ BEGIN PERFORM '$$;'; END $body$;\nSELECT $1;",
+			want: []Statement{
+				{SQL: "DO $body$ BEGIN PERFORM '$$;'; END $body$", Line: 1},
+				{SQL: "SELECT $1", Line: 2},
+			},
+		},
+		{
+			name:   "postgres # operator",
+			dbKind: []dbsys{Postgres},
+			src:    "SELECT '{\"a\":1}'::jsonb #> '{a}';\nSELECT 5 # 3;",
+			want: []Statement{
+				{SQL: "SELECT '{\"a\":1}'::jsonb #> '{a}'", Line: 1},
+				{SQL: "SELECT 5 # 3", Line: 2},
+			},
+		},
+		{
+			name: "delimiter",
+			src:  "DELIMITER $$\nCREATE PROCEDURE p()\nBEGIN\n\tSELECT 1;\n\tSELECT 2;\nEND$$\nDELIMITER ;\nCALL p();",
+			want: []Statement{
+				{SQL: "CREATE PROCEDURE p()\nBEGIN\n\tSELECT 1;\n\tSELECT 2;\nEND", Line: 2},
+				{SQL: "CALL p()", Line: 8},
+			},
+		},
+		{
+			name: "trigger without delimiter",
+			src:  "CREATE TRIGGER tr AFTER INSERT ON a\nBEGIN\n\tUPDATE b SET n = n + 1;\n\tSELECT CASE WHEN 1 THEN 2 END;\nEND;\nSELECT 1;",
+			want: []Statement{
+				{SQL: "CREATE TRIGGER tr AFTER INSERT ON a\nBEGIN\n\tUPDATE b SET n = n + 1;\n\tSELECT CASE WHEN 1 THEN 2 END;\nEND", Line: 1},
+				{SQL: "SELECT 1", Line: 6},
+			},
+		},
+		{
+			name: "mysql procedure with end if",
+			src:  "CREATE PROCEDURE p(x int)\nBEGIN\n\tIF x > 0 THEN\n\t\tSELECT 1;\n\tEND IF;\nEND;\nCALL p(1);",
+			want: []Statement{
+				{SQL: "CREATE PROCEDURE p(x int)\nBEGIN\n\tIF x > 0 THEN\n\t\tSELECT 1;\n\tEND IF;\nEND", Line: 1},
+				{SQL: "CALL p(1)", Line: 7},
+			},
+		},
+		{
+			name:   "mysql backslash escapes",
+			dbKind: []dbsys{Mysql},
+			src:    "INSERT INTO t VALUES ('it\\'s; here', \"a\\\"; b\", 'c:\\\\');\nSELECT 2;",
+			want: []Statement{
+				{SQL: "INSERT INTO t VALUES ('it\\'s; here', \"a\\\"; b\", 'c:\\\\')", Line: 1},
+				{SQL: "SELECT 2", Line: 2},
+			},
+		},
+		{
+			name:   "mysql escaped newline",
+			dbKind: []dbsys{Mysql},
+			src:    "SELECT 'a\\\nb';\nSELECT 2;",
+			want: []Statement{
+				{SQL: "SELECT 'a\\\nb'", Line: 1},
+				{SQL: "SELECT 2", Line: 3},
+			},
+		},
+		{
+			name:   "mysql hash comments",
+			dbKind: []dbsys{Mysql},
+			src:    "# comment ;\nSELECT 1; # trailing ;\nSELECT '#';",
+			want: []Statement{
+				{SQL: "SELECT 1", Line: 2},
+				{SQL: "SELECT '#'", Line: 3},
+			},
+		},
+		{
+			name:   "mysql backticks don't use backslash escapes",
+			dbKind: []dbsys{Mysql},
+			src:    "SELECT `a\\`;\nSELECT 2;",
+			want: []Statement{
+				{SQL: "SELECT `a\\`", Line: 1},
+				{SQL: "SELECT 2", Line: 2},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := SplitStatements(strings.NewReader(test.src), test.dbKind...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitStatementsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		dbKind []dbsys
+		src    string
+		want   string
+	}{
+		{name: "unterminated literal", src: "SELECT 1;\nSELECT 'a;", want: "line 2: unterminated '"},
+		{name: "unterminated identifier", src: "SELECT \"a;", want: "line 1: unterminated \""},
+		{name: "unterminated comment", src: "SELECT 1;\n/* a\n;", want: "line 2: unterminated comment"},
+		{name: "unterminated dollar quote", src: "DO $$ BEGIN;", want: "line 1: unterminated dollar quoted string"},
+		{name: "missing delimiter", src: "DELIMITER\nSELECT 1;", want: "line 1: missing delimiter"},
+		{name: "escaped quote in mysql", dbKind: []dbsys{Mysql}, src: "SELECT 'a\\';", want: "line 1: unterminated '"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := SplitStatements(strings.NewReader(test.src), test.dbKind...)
+			if !errors.Is(err, ErrInvalidSQLFile) {
+				t.Fatalf("got %v, want %v", err, ErrInvalidSQLFile)
+			}
+			if !strings.HasSuffix(err.Error(), test.want) {
+				t.Errorf("got %q, want suffix %q", err.Error(), test.want)
+			}
+		})
+	}
+}
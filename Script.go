@@ -0,0 +1,297 @@
+package godbhelper
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ExportScript writes the queries of all added chains newer than from as SQL script
+//for the database system dbKind into w. This is intended for databases which get
+//migrated by hand. Params are inlined as escaped literals and Fparams formatted.
+//The versions of the chains (and the checksums of repeatable queries, which are
+//always included) are stored in the same transactions like RunUpdate does.
+//The migration history isn't written. Queries written in Go (Func) can't be exported
+func (dbhelper *DBhelper) ExportScript(w io.Writer, dbKind dbsys, from Version) error {
+	name, ok := dialectNames[dbKind]
+	if !ok {
+		return ErrDBNotSupported
+	}
+
+	if err := dbhelper.sortQueryChains(); err != nil {
+		return err
+	}
+
+	script := scriptWriter{dbKind: dbKind}
+	script.line("-- GoDBHelper migration script for %s", name)
+	script.line("-- Queries newer than v.%s", from)
+	script.line("")
+	script.statement(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s %s", TableDBVersion, versionTableColumns))
+	script.statement(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s %s", TableRepeatable, repeatableTableColumns))
+
+	for _, chain := range dbhelper.QueryChains {
+		var queries []SQLQuery
+		for _, query := range dialectQueriesFor(dbKind, chain.Queries) {
+			if !query.Repeatable && query.hasUp() && from.Less(query.VersionAdded) {
+				queries = append(queries, query)
+			}
+		}
+
+		for _, step := range dbhelper.splitUpdateSteps(queries) {
+			version := step[len(step)-1].VersionAdded
+			script.line("")
+			script.line("-- chain '%s' v.%s", chain.Name, version)
+			if err := script.transaction(chain.Name, step, func() {
+				script.storeRow(TableDBVersion, 1, "chain", chain.Name, "version", version.String())
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	//Repeatable queries run after all versioned ones
+	for _, chain := range dbhelper.QueryChains {
+		groups, err := repeatableGroups(chain.Name, dialectQueriesFor(dbKind, chain.Queries))
+		if err != nil {
+			return err
+		}
+
+		for _, group := range groups {
+			script.line("")
+			script.line("-- chain '%s' repeatable '%s'", chain.Name, group[0].Name)
+			if err := script.transaction(chain.Name, group, func() {
+				script.storeRow(TableRepeatable, 2, "chain", chain.Name, "name", group[0].Name, "checksum", repeatableChecksum(group))
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, script.String())
+	return err
+}
+
+//scriptWriter builds the script of ExportScript
+type scriptWriter struct {
+	strings.Builder
+	dbKind dbsys
+}
+
+//line writes a formatted line
+func (script *scriptWriter) line(format string, args ...interface{}) {
+	script.WriteString(fmt.Sprintf(format, args...) + "\n")
+}
+
+//statement writes a statement terminated by ';'. MySQL statements
+//containing ';' (eg. triggers) are wrapped into DELIMITER lines
+func (script *scriptWriter) statement(statement string) {
+	statement = strings.TrimRight(strings.TrimSpace(statement), ";")
+
+	//Don't comment out the delimiter
+	if strings.Contains(statement[strings.LastIndexByte(statement, '\n')+1:], "--") {
+		statement += "\n"
+	}
+
+	if script.dbKind == Mysql && strings.Contains(statement, ";") {
+		delimiter := "$$"
+		if strings.Contains(statement, delimiter) {
+			delimiter = "//"
+		}
+		script.line("DELIMITER %s", delimiter)
+		script.line("%s%s", statement, delimiter)
+		script.line("DELIMITER ;")
+		return
+	}
+
+	script.line("%s;", statement)
+}
+
+//transaction writes queries of chain and the statements of store in a transaction
+func (script *scriptWriter) transaction(chain string, queries []SQLQuery, store func()) error {
+	begin := "BEGIN"
+	switch script.dbKind {
+	case Sqlite, SqliteEncrypted:
+		begin = "BEGIN TRANSACTION"
+	case Mysql:
+		begin = "START TRANSACTION"
+	}
+	script.statement(begin)
+
+	for _, query := range queries {
+		if query.Func != nil {
+			return fmt.Errorf("%w: chain '%s' v.%s %s", ErrFuncNotScriptable, chain, query.VersionAdded, query.sql())
+		}
+
+		statement, err := script.inline(query.sql(), query.Params)
+		if err != nil {
+			return fmt.Errorf("chain '%s' v.%s \"%s\": %w", chain, query.VersionAdded, query.sql(), err)
+		}
+		script.statement(statement)
+	}
+
+	store()
+	script.statement("COMMIT")
+	return nil
+}
+
+//storeRow writes the statements replacing the row of table identified by its first keys columns.
+//columnValues contains the names of the columns, each followed by its value
+func (script *scriptWriter) storeRow(table string, keys int, columnValues ...string) {
+	var columns, values []string
+	for i := 0; i+1 < len(columnValues); i += 2 {
+		columns = append(columns, columnValues[i])
+		values = append(values, script.quote(columnValues[i+1]))
+	}
+
+	var conditions []string
+	for i := 0; i < keys; i++ {
+		conditions = append(conditions, columns[i]+" = "+values[i])
+	}
+
+	script.statement(fmt.Sprintf("DELETE FROM %s WHERE %s", table, strings.Join(conditions, " AND ")))
+	script.statement(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), strings.Join(values, ", ")))
+}
+
+//inline replaces the placeholders (? or $1) of statement by the escaped params.
+//Placeholders in literals, quoted identifiers and comments are ignored
+func (script *scriptWriter) inline(statement string, params QueryParams) (string, error) {
+	if len(params) == 0 {
+		return statement, nil
+	}
+
+	literals := make([]string, len(params))
+	for i, param := range params {
+		literal, err := script.literal(param)
+		if err != nil {
+			return "", fmt.Errorf("%w: param %d: %s", ErrInvalidQueryParam, i+1, err)
+		}
+		literals[i] = literal
+	}
+
+	scanner := sqlSplitter{src: statement, line: 1, delimiter: ";", mysql: script.dbKind == Mysql}
+	src := scanner.src
+
+	var out strings.Builder
+	var copied, next int
+	numbered := false
+	for scanner.pos < len(src) {
+		c := src[scanner.pos]
+		rest := src[scanner.pos:]
+
+		var err error
+		switch {
+		case strings.HasPrefix(rest, "--") || (c == '#' && scanner.mysql):
+			scanner.skipLine()
+		case strings.HasPrefix(rest, "/*"):
+			err = scanner.skipBlock(2, "*/", "comment")
+		case c == '\'' || c == '"' || c == '`':
+			err = scanner.skipQuoted(c)
+		case c == '$' && scanner.dollarTag() != "":
+			tag := scanner.dollarTag()
+			err = scanner.skipBlock(len(tag), tag, "dollar quoted string")
+		case c == '?':
+			if next >= len(literals) {
+				return "", fmt.Errorf("%w: more placeholders than params", ErrInvalidQueryParam)
+			}
+			out.WriteString(src[copied:scanner.pos])
+			out.WriteString(literals[next])
+			next++
+			scanner.pos++
+			copied = scanner.pos
+		case c == '$' && (scanner.pos == 0 || !isWordChar(src[scanner.pos-1])) &&
+			scanner.pos+1 < len(src) && src[scanner.pos+1] >= '0' && src[scanner.pos+1] <= '9':
+			end := scanner.pos + 1
+			for end < len(src) && src[end] >= '0' && src[end] <= '9' {
+				end++
+			}
+
+			n, _ := strconv.Atoi(src[scanner.pos+1 : end])
+			if n < 1 || n > len(literals) {
+				return "", fmt.Errorf("%w: no param for $%d", ErrInvalidQueryParam, n)
+			}
+			out.WriteString(src[copied:scanner.pos])
+			out.WriteString(literals[n-1])
+			numbered = true
+			scanner.pos = end
+			copied = end
+		case isWordChar(c):
+			scanner.readWord()
+		default:
+			scanner.pos++
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+	out.WriteString(src[copied:])
+
+	if !numbered && next != len(literals) {
+		return "", fmt.Errorf("%w: %d placeholders for %d params", ErrInvalidQueryParam, next, len(literals))
+	}
+
+	return out.String(), nil
+}
+
+//literal returns param as escaped SQL literal
+func (script *scriptWriter) literal(param interface{}) (string, error) {
+	switch v := param.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return script.quote(v), nil
+	case bool:
+		if script.dbKind == Sqlite || script.dbKind == SqliteEncrypted {
+			if v {
+				return "1", nil
+			}
+			return "0", nil
+		}
+		return strings.ToUpper(strconv.FormatBool(v)), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	case float32:
+		return script.literal(float64(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", fmt.Errorf("unsupported number %v", v)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case []byte:
+		if script.dbKind == Postgres {
+			return `'\x` + hex.EncodeToString(v) + "'::bytea", nil
+		}
+		return "X'" + hex.EncodeToString(v) + "'", nil
+	case time.Time:
+		//Use the formats of the drivers
+		switch script.dbKind {
+		case Mysql:
+			return script.quote(v.UTC().Format("2006-01-02 15:04:05.999999")), nil
+		case Postgres:
+			return script.quote(v.Format("2006-01-02 15:04:05.999999-07:00")), nil
+		}
+		return script.quote(v.Format("2006-01-02 15:04:05.999999999-07:00")), nil
+	case driver.Valuer:
+		value, err := v.Value()
+		if err != nil {
+			return "", err
+		}
+		return script.literal(value)
+	}
+
+	return "", fmt.Errorf("unsupported type %T", param)
+}
+
+//quote returns s as escaped string literal
+func (script *scriptWriter) quote(s string) string {
+	//MySQL uses backslashes as escape character in literals
+	if script.dbKind == Mysql {
+		s = strings.NewReplacer(`\`, `\\`, "\x00", `\0`).Replace(s)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
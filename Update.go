@@ -0,0 +1,633 @@
+package godbhelper
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jmoiron/sqlx"
+)
+
+//updateStep queries of a chain which run in one transaction.
+//version is stored as new version of the chain in the same transaction
+type updateStep struct {
+	chain   string
+	queries []SQLQuery
+	version Version
+	down    bool
+	//repeatable steps store the checksums of their queries instead of a version
+	repeatable bool
+}
+
+//RunUpdate updates new sql queries
+//RunUpdate(fullUpdate, dropAllTables bool)
+//dropAllTables drops every table and view of the database, including the VersionStore, before
+//updating. Sequences, functions and procedures which don't belong to a table are kept.
+//Every chain is updated from its own version in the VersionStore. Every version
+//of a chain (or the whole chain, see DBhelperOptions.TransactionMode) runs in a
+//transaction which also stores the new version of the chain. MySQL can't
+//roll back DDL statements, see TransactionMode.
+//If queries fail and StopUpdateOnError isn't set, the remaining steps still run
+//and an *UpdateError containing every failed query is returned.
+//Invalid definitions (see Validate) return a *ValidationError before anything runs
+func (dbhelper *DBhelper) RunUpdate(options ...bool) error {
+	_, err := dbhelper.RunUpdateWithReport(options...)
+	return err
+}
+
+//RunUpdateWithReport like RunUpdate but returns an UpdateReport
+//containing every executed query. The report is nil if the update
+//couldn't start. Errors are always returned, the ErrHook is only
+//notified (ErrHookOptions.ReturnNilOnErr doesn't apply)
+func (dbhelper *DBhelper) RunUpdateWithReport(options ...bool) (*UpdateReport, error) {
+	if !dbhelper.Options.StoreVersionInDB {
+		return nil, ErrCantStoreVersionInDB
+	}
+	dbhelper.checkColors()
+
+	//Don't start with broken definitions
+	if err := dbhelper.Validate(); err != nil {
+		return nil, err
+	}
+
+	//Only one process may update at a time
+	unlock, err := dbhelper.lockMigrations()
+	if err != nil {
+		dbhelper.handleErrHook(err, "locking migrations")
+		return nil, err
+	}
+	defer unlock()
+
+	//Versions might have been changed by another process while waiting
+	if err = dbhelper.initDBVersion(); err != nil {
+		dbhelper.handleErrHook(err, "loading versions")
+		return nil, err
+	}
+
+	if err = dbhelper.sortQueryChains(); err != nil {
+		return nil, err
+	}
+
+	//Check options
+	var fullUpdate, dropAllTables bool
+	for i, v := range options {
+		switch i {
+		case 0:
+			fullUpdate = v
+		case 1:
+			dropAllTables = v
+		}
+	}
+
+	//Print info
+	if dbhelper.Options.Debug {
+		var add string
+		if fullUpdate {
+			add = "full"
+		}
+		fmt.Printf("Updating database %s\n", add)
+	}
+
+	start := time.Now()
+	report := &UpdateReport{
+		OldVersion: dbhelper.CurrentVersion,
+	}
+
+	//Drop everything and recreate the VersionStore to run every query again
+	if dropAllTables {
+		if err := dbhelper.dropAllTables(); err != nil {
+			dbhelper.handleErrHook(err, "dropping tables")
+			return nil, err
+		}
+		if err := dbhelper.initDBVersion(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := dbhelper.adoptLegacyVersion(); err != nil {
+		dbhelper.handleErrHook(err, "adopting version of "+TableDBVersion)
+		return nil, err
+	}
+
+	//Queries run again anyway on a full update
+	if !fullUpdate {
+		drifts, err := dbhelper.checkDrift()
+		if err != nil {
+			return nil, err
+		}
+		report.Drifts = drifts
+	}
+
+	repeatables, err := dbhelper.pendingRepeatables(fullUpdate)
+	if err != nil {
+		dbhelper.handleErrHook(err, "loading "+TableRepeatable)
+		return nil, err
+	}
+
+	//Hooks can abort the update by returning an error
+	if err = dbhelper.runHooks(HookBeforeUpdate, HookEvent{Version: dbhelper.CurrentVersion}); err != nil {
+		return nil, err
+	}
+
+	var failed []*QueryError
+	var chainReports []ChainReport
+	var aborted error
+	failedChains := make(map[string]bool)
+
+	if dbhelper.Options.Debug {
+		fmt.Println()
+	}
+
+	for _, chain := range dbhelper.QueryChains {
+		if dbhelper.Options.Debug {
+			color.New(color.Underline).Println("chain:", chain.Name)
+		}
+
+		chainReport := ChainReport{
+			Name:       chain.Name,
+			OldVersion: dbhelper.ChainVersion(chain.Name),
+		}
+
+		if aborted = dbhelper.runHooks(HookBeforeChain, HookEvent{Chain: chain.Name, Version: chainReport.OldVersion}); aborted != nil {
+			break
+		}
+
+		var chainErr error
+		for _, queries := range dbhelper.splitUpdateSteps(dbhelper.pendingQueries(chain, fullUpdate)) {
+			queryReports, err := dbhelper.runUpdateStep(updateStep{
+				chain:   chain.Name,
+				queries: queries,
+				version: queries[len(queries)-1].VersionAdded,
+			})
+			chainReport.Queries = append(chainReport.Queries, queryReports...)
+
+			if err != nil {
+				failedChains[chain.Name] = true
+				var queryErr *QueryError
+				if !errors.As(err, &queryErr) {
+					//Aborted by a hook
+					aborted = err
+					break
+				}
+
+				failed = append(failed, queryErr)
+				if chainErr == nil {
+					chainErr = queryErr
+				}
+
+				//Don't move the version of the chain past the failed query
+				if dbhelper.Options.StopUpdateOnError || dbhelper.Options.HoldVersionOnError {
+					break
+				}
+			}
+		}
+
+		chainReport.NewVersion = dbhelper.ChainVersion(chain.Name)
+		chainReports = append(chainReports, chainReport)
+
+		if dbhelper.Options.Debug && len(chainReport.Queries) > 0 {
+			fmt.Println()
+		}
+
+		if aborted != nil {
+			break
+		}
+
+		if aborted = dbhelper.runHooks(HookAfterChain, HookEvent{Chain: chain.Name, Version: chainReport.NewVersion, Err: chainErr}); aborted != nil {
+			break
+		}
+
+		if len(failed) > 0 && dbhelper.Options.StopUpdateOnError {
+			break
+		}
+	}
+
+	//Repeatable queries usually depend on the tables created by
+	//versioned queries, so they run after all of them
+	for i := range chainReports {
+		if aborted != nil || (len(failed) > 0 && dbhelper.Options.StopUpdateOnError) {
+			break
+		}
+
+		//The changes a repeatable query depends on might be missing
+		chain := chainReports[i].Name
+		if failedChains[chain] {
+			continue
+		}
+
+		for _, queries := range repeatables[chain] {
+			queryReports, err := dbhelper.runUpdateStep(updateStep{
+				chain:      chain,
+				queries:    queries,
+				version:    dbhelper.ChainVersion(chain),
+				repeatable: true,
+			})
+			chainReports[i].Queries = append(chainReports[i].Queries, queryReports...)
+
+			if err != nil {
+				var queryErr *QueryError
+				if !errors.As(err, &queryErr) {
+					aborted = err
+					break
+				}
+
+				failed = append(failed, queryErr)
+				if dbhelper.Options.StopUpdateOnError {
+					break
+				}
+			}
+		}
+	}
+
+	for _, chainReport := range chainReports {
+		report.addChain(chainReport)
+	}
+
+	report.NewVersion = dbhelper.CurrentVersion
+	report.Duration = time.Since(start)
+
+	if dbhelper.Options.Debug {
+		msg := "Updated %d Database queries with errors\n"
+		if len(failed) == 0 && aborted == nil {
+			msg = "Successfully updated %d Database queries\n"
+		}
+		fmt.Printf(msg, report.Succeeded+report.Failed+report.RolledBack)
+	}
+
+	updateErr := aborted
+	if updateErr == nil && len(failed) > 0 {
+		if dbhelper.Options.StopUpdateOnError {
+			updateErr = failed[0]
+		} else {
+			updateErr = &UpdateError{Errors: failed}
+		}
+	}
+
+	if err = dbhelper.runHooks(HookAfterUpdate, HookEvent{Version: dbhelper.CurrentVersion, Err: updateErr}); err != nil && updateErr == nil {
+		updateErr = err
+	}
+	return report, updateErr
+}
+
+//sortQueryChains sorts the chains and their queries in the order they have to run.
+//Chains run after the chains they depend on, Order decides between independent chains
+func (dbhelper *DBhelper) sortQueryChains() error {
+	sort.SliceStable(dbhelper.QueryChains, func(i, j int) bool {
+		return dbhelper.QueryChains[i].Order < dbhelper.QueryChains[j].Order
+	})
+
+	sorted, err := sortByDependencies(dbhelper.QueryChains)
+	if err != nil {
+		return err
+	}
+	dbhelper.QueryChains = sorted
+
+	for _, chain := range dbhelper.QueryChains {
+		sort.SliceStable(chain.Queries, func(i, j int) bool {
+			return chain.Queries[i].VersionAdded.Less(chain.Queries[j].VersionAdded)
+		})
+	}
+	return nil
+}
+
+//pendingQueries returns the queries of a sorted chain which aren't applied yet.
+//fullUpdate returns all queries
+func (dbhelper *DBhelper) pendingQueries(chain QueryChain, fullUpdate bool) []SQLQuery {
+	//Start below version 0 to run every query
+	startVersion := dbhelper.ChainVersion(chain.Name)
+	if fullUpdate {
+		startVersion = NoVersion
+	}
+
+	var pending []SQLQuery
+	for _, query := range dbhelper.versionedQueries(chain) {
+		if !query.hasUp() {
+			continue
+		}
+
+		if startVersion.Less(query.VersionAdded) {
+			pending = append(pending, query)
+		}
+	}
+
+	return pending
+}
+
+//splitUpdateSteps splits the sorted queries of a chain into the
+//groups which are run in one transaction each
+func (dbhelper *DBhelper) splitUpdateSteps(queries []SQLQuery) [][]SQLQuery {
+	if len(queries) == 0 {
+		return nil
+	}
+
+	if dbhelper.Options.TransactionMode == TxPerChain {
+		return [][]SQLQuery{queries}
+	}
+
+	return splitByVersion(queries)
+}
+
+//splitByVersion splits sorted queries into groups of the same version
+func splitByVersion(queries []SQLQuery) [][]SQLQuery {
+	var groups [][]SQLQuery
+	start := 0
+	for i := 1; i <= len(queries); i++ {
+		if i == len(queries) || queries[i].VersionAdded.Compare(queries[start].VersionAdded) != 0 {
+			groups = append(groups, queries[start:i])
+			start = i
+		}
+	}
+	return groups
+}
+
+//runUpdateStep runs the queries of step in a transaction and saves the version
+//of the chain in the same transaction. Everything gets rolled back if a query fails,
+//except DDL statements on MySQL which commit implicitly (see TransactionMode).
+//Every executed query is written into the migration history
+func (dbhelper *DBhelper) runUpdateStep(step updateStep) ([]QueryReport, error) {
+	tx, err := dbhelper.DB.Beginx()
+	if err != nil {
+		dbhelper.handleErrHook(err, "beginning transaction")
+		return nil, step.error(err, nil)
+	}
+
+	kind := MigrationUp
+	if step.down {
+		kind = MigrationDown
+	} else if step.repeatable {
+		kind = MigrationRepeatable
+	}
+
+	var reports []QueryReport
+	index := 0
+	for i, query := range step.queries {
+		//Index queries within their version
+		if i > 0 && query.VersionAdded.Compare(step.queries[i-1].VersionAdded) == 0 {
+			index++
+		} else {
+			index = 0
+		}
+
+		sql := query.sql()
+		if dbhelper.Options.Debug {
+			label := "v." + query.VersionAdded.String()
+			if step.repeatable {
+				label = query.Name
+			}
+			fmt.Print(label, ":\t\"", sql, "\"", query.Params)
+		}
+
+		record := MigrationRecord{
+			Chain:     step.chain,
+			Version:   query.VersionAdded,
+			Index:     index,
+			Kind:      kind,
+			Checksum:  checksum(sql),
+			Statement: sql,
+			AppliedAt: time.Now(),
+		}
+
+		event := HookEvent{
+			Chain:   step.chain,
+			Version: query.VersionAdded,
+			SQL:     sql,
+			Params:  query.Params,
+			Down:    step.down,
+			Tx:      tx,
+		}
+		if err = dbhelper.runHooks(HookBeforeQuery, event); err != nil {
+			tx.Rollback()
+			return rolledBack(reports, nil), err
+		}
+
+		rowsAffected, err := execQuery(dbhelper, tx, query)
+		record.Duration = time.Since(record.AppliedAt)
+		reports = append(reports, QueryReport{
+			Version:      query.VersionAdded,
+			SQL:          sql,
+			Status:       QuerySucceeded,
+			Duration:     record.Duration,
+			RowsAffected: rowsAffected,
+		})
+
+		if err != nil {
+			if dbhelper.Options.Debug {
+				fmt.Printf(" -> %s\n", color.New(color.FgRed).SprintFunc()(" ERROR: "+err.Error()))
+			}
+			tx.Rollback()
+
+			//Record the failure outside of the rolled back transaction
+			record.Error = err.Error()
+			dbhelper.addMigrationRecord(dbhelper.DB, record)
+
+			reports[i].Status = QueryFailed
+			reports[i].Error = err.Error()
+			dbhelper.handleErrHook(err, sql)
+			return rolledBack(reports[:i], reports[i:]), step.error(err, &query)
+		}
+
+		if err = dbhelper.runHooks(HookAfterQuery, event); err != nil {
+			tx.Rollback()
+			return rolledBack(reports, nil), err
+		}
+
+		record.Success = true
+		if err = dbhelper.addMigrationRecord(tx, record); err != nil {
+			tx.Rollback()
+			dbhelper.handleErrHook(err, "writing "+TableMigrationHistory)
+			return rolledBack(reports, nil), step.error(err, &query)
+		}
+
+		if dbhelper.Options.Debug {
+			fmt.Printf(" -> %s\n", color.New(color.FgGreen).SprintFunc()("success"))
+		}
+	}
+
+	//Store the version or the checksum of repeatable queries in the same transaction
+	if step.repeatable {
+		if err = dbhelper.saveRepeatable(tx, step.chain, step.queries); err != nil {
+			tx.Rollback()
+			dbhelper.handleErrHook(err, "writing "+TableRepeatable)
+			return rolledBack(reports, nil), step.error(err, nil)
+		}
+	} else if err = dbhelper.saveVersion(tx, step.chain, step.version); err != nil {
+		tx.Rollback()
+		dbhelper.handleErrHook(err, "saving version")
+		return rolledBack(reports, nil), step.error(err, nil)
+	}
+
+	if err = tx.Commit(); err != nil {
+		dbhelper.handleErrHook(err, "committing transaction")
+		return rolledBack(reports, nil), step.error(err, nil)
+	}
+
+	if !step.repeatable {
+		dbhelper.setChainVersion(step.chain, step.version)
+	}
+	return reports, nil
+}
+
+//rolledBack marks the succeeded reports as rolled back and appends rest
+func rolledBack(reports []QueryReport, rest []QueryReport) []QueryReport {
+	for i := range reports {
+		reports[i].Status = QueryRolledBack
+	}
+	return append(reports, rest...)
+}
+
+//error wraps err of step into a QueryError. query is the failed query,
+//nil if the transaction of the step failed
+func (step updateStep) error(err error, query *SQLQuery) error {
+	queryErr := &QueryError{
+		Chain:   step.chain,
+		Version: step.version,
+		Err:     err,
+	}
+
+	if query != nil {
+		queryErr.Version = query.VersionAdded
+		queryErr.SQL = query.sql()
+	}
+
+	return queryErr
+}
+
+//execQuery executes query in tx and returns the count of affected rows.
+//The error of the database is returned as it is, the ErrHook is only
+//notified by the caller, so it can't hide a failed query
+func execQuery(dbhelper *DBhelper, tx *sqlx.Tx, query SQLQuery) (int64, error) {
+	if query.Func != nil {
+		return 0, query.Func(dbhelper, tx)
+	}
+
+	res, err := tx.Exec(query.sql(), query.Params...)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	return rowsAffected, nil
+}
+
+//RunDowngrade undoes all versions newer than target in every chain using the
+//down statements of the queries. Chains are undone in the reverse order of
+//RunUpdate, so chains get undone before the chains they depend on. The versions
+//of a chain are undone from the newest to the oldest one, the queries of a
+//version in reverse order. Each version of
+//a chain runs in a transaction which also stores the next lower version of the
+//chain. Queries without a down statement are skipped, but every undone version
+//of a chain needs at least one. Hooks run like in RunUpdate with HookEvent.Down set,
+//chain hooks only for chains with versions to undo
+func (dbhelper *DBhelper) RunDowngrade(target Version) error {
+	if !dbhelper.Options.StoreVersionInDB {
+		return ErrCantStoreVersionInDB
+	}
+	dbhelper.checkColors()
+
+	unlock, err := dbhelper.lockMigrations()
+	if err != nil {
+		dbhelper.handleErrHook(err, "locking migrations")
+		return err
+	}
+	defer unlock()
+
+	if err = dbhelper.initDBVersion(); err != nil {
+		dbhelper.handleErrHook(err, "loading versions")
+		return err
+	}
+
+	if dbhelper.Options.Debug {
+		fmt.Printf("Downgrading database to v.%s\n\n", target)
+	}
+
+	if err := dbhelper.adoptLegacyVersion(); err != nil {
+		dbhelper.handleErrHook(err, "adopting version of "+TableDBVersion)
+		return err
+	}
+
+	//Collect down statements, chains and queries in reverse order.
+	//Versions of different chains aren't related, so steps stay grouped by chain
+	if err := dbhelper.sortQueryChains(); err != nil {
+		return err
+	}
+	var steps []updateStep
+	for i := len(dbhelper.QueryChains) - 1; i >= 0; i-- {
+		chain := dbhelper.QueryChains[i]
+		chainVersion := dbhelper.ChainVersion(chain.Name)
+		if !target.Less(chainVersion) {
+			continue
+		}
+
+		//Collect versions to undo, newest first
+		groups := splitByVersion(dbhelper.versionedQueries(chain))
+		for j := len(groups) - 1; j >= 0; j-- {
+			version := groups[j][0].VersionAdded
+			if !target.Less(version) || chainVersion.Less(version) {
+				continue
+			}
+
+			step := updateStep{
+				chain:   chain.Name,
+				version: target,
+				down:    true,
+			}
+			if j > 0 && target.Less(groups[j-1][0].VersionAdded) {
+				step.version = groups[j-1][0].VersionAdded
+			}
+
+			for k := len(groups[j]) - 1; k >= 0; k-- {
+				if groups[j][k].hasDown() {
+					step.queries = append(step.queries, groups[j][k].downQuery())
+				}
+			}
+
+			if len(step.queries) == 0 {
+				return fmt.Errorf("%w: chain '%s' v.%s", ErrNoDownQuery, chain.Name, version)
+			}
+			steps = append(steps, step)
+		}
+	}
+
+	if err = dbhelper.runHooks(HookBeforeUpdate, HookEvent{Version: dbhelper.CurrentVersion, Down: true}); err != nil {
+		return err
+	}
+
+	var c int
+	for i, step := range steps {
+		//Chain hooks surround the steps of each chain
+		if i == 0 || steps[i-1].chain != step.chain {
+			if err = dbhelper.runHooks(HookBeforeChain, HookEvent{Chain: step.chain, Version: dbhelper.ChainVersion(step.chain), Down: true}); err != nil {
+				break
+			}
+		}
+
+		if _, err = dbhelper.runUpdateStep(step); err == nil {
+			c += len(step.queries)
+		}
+
+		if err != nil || i == len(steps)-1 || steps[i+1].chain != step.chain {
+			hookErr := dbhelper.runHooks(HookAfterChain, HookEvent{Chain: step.chain, Version: dbhelper.ChainVersion(step.chain), Down: true, Err: err})
+			if err == nil {
+				err = hookErr
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if hookErr := dbhelper.runHooks(HookAfterUpdate, HookEvent{Version: dbhelper.CurrentVersion, Down: true, Err: err}); hookErr != nil && err == nil {
+		err = hookErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if dbhelper.Options.Debug {
+		fmt.Printf("\nSuccessfully undid %d Database queries\n", c)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package godbhelper
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+//formatErrorRegex matches the markers fmt writes for missing, extra or bad verbs
+var formatErrorRegex = regexp.MustCompile(`%!([a-zA-Z]?\((MISSING|BADINDEX|NOVERB)\)|\(EXTRA |[a-zA-Z]\(string=)`)
+
+//Validate checks the definitions of all added QueryChains. Chain names have
+//to be unique and dependencies have to exist without forming a cycle. See
+//QueryChain.Validate for the checks of the queries. RunUpdate calls Validate
+//before changing anything. A *ValidationError containing every problem is returned
+func (dbhelper *DBhelper) Validate() error {
+	var errs []*ChainError
+
+	chains := make(map[string]bool, len(dbhelper.QueryChains))
+	for _, chain := range dbhelper.QueryChains {
+		if chains[chain.Name] {
+			errs = append(errs, &ChainError{Chain: chain.Name, Query: -1, Err: ErrDuplicateChain})
+		}
+		chains[chain.Name] = true
+	}
+
+	missingDependency := false
+	for _, chain := range dbhelper.QueryChains {
+		errs = append(errs, chain.validate()...)
+
+		for _, dependency := range chain.DependsOn {
+			if !chains[dependency] {
+				missingDependency = true
+				errs = append(errs, &ChainError{
+					Chain: chain.Name,
+					Query: -1,
+					Err:   fmt.Errorf("%w: '%s'", ErrMissingDependency, dependency),
+				})
+			}
+		}
+	}
+
+	//Cycles can only be searched if all dependencies exist
+	if !missingDependency {
+		if _, err := sortByDependencies(dbhelper.QueryChains); err != nil {
+			errs = append(errs, &ChainError{Query: -1, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+//Validate checks the queries of queryChain. Queries need a statement, a Func or a
+//variant (down only queries are allowed), a valid version which isn't negative and
+//as many Fparams as their formatted statements use. Equal versions have to be
+//spelled the same and repeatable queries need a Name.
+//A *ValidationError containing every problem is returned
+func (queryChain *QueryChain) Validate() error {
+	if errs := queryChain.validate(); len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+//validate returns the problems of the queries of queryChain
+func (queryChain QueryChain) validate() []*ChainError {
+	var errs []*ChainError
+	addErr := func(i int, err error) {
+		errs = append(errs, &ChainError{
+			Chain:   queryChain.Name,
+			Query:   i,
+			Version: queryChain.Queries[i].VersionAdded,
+			Err:     err,
+		})
+	}
+
+	//The first spelling of every version
+	spellings := make(map[string]Version)
+	for i, query := range queryChain.Queries {
+		if !query.hasUp() && !query.hasDown() && len(query.Variants) == 0 {
+			addErr(i, ErrEmptyQuery)
+		}
+
+		if query.Repeatable {
+			if len(query.Name) == 0 {
+				addErr(i, ErrUnnamedRepeatable)
+			}
+		} else if err := validateQueryVersion(query.VersionAdded); err != nil {
+			addErr(i, err)
+		} else {
+			canonical := query.VersionAdded.canonical()
+			if spelling, ok := spellings[canonical]; !ok {
+				spellings[canonical] = query.VersionAdded
+			} else if spelling.String() != query.VersionAdded.String() {
+				addErr(i, fmt.Errorf("%w: v.%s and v.%s", ErrAmbiguousVersion, spelling, query.VersionAdded))
+			}
+		}
+
+		for _, err := range validateFparams(query.Fparams, query.FqueryString, query.FdownQueryString) {
+			addErr(i, err)
+		}
+
+		names := make([]string, 0, len(query.Variants))
+		for name := range query.Variants {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			variant := query.Variants[name]
+			if !isDialectName(name) {
+				addErr(i, fmt.Errorf("%w: '%s'", ErrInvalidVariant, name))
+				continue
+			}
+
+			for _, err := range validateFparams(variant.Fparams, variant.FqueryString, variant.FdownQueryString) {
+				addErr(i, fmt.Errorf("variant '%s': %w", name, err))
+			}
+		}
+	}
+
+	return errs
+}
+
+//validateQueryVersion returns an error if version can't be parsed or is negative
+func validateQueryVersion(version Version) error {
+	parts, err := version.parts()
+	if err != nil {
+		return err
+	}
+	if parts[0] < 0 {
+		return fmt.Errorf("%w: '%s' is negative", ErrInvalidVersion, version)
+	}
+	return nil
+}
+
+//validateFparams returns an error for every format which doesn't use exactly all fparams
+func validateFparams(fparams []string, formats ...string) []error {
+	//Format with empty params, so only the format can produce the markers
+	params := make([]interface{}, len(fparams))
+	for i := range params {
+		params[i] = ""
+	}
+
+	var errs []error
+	for _, format := range formats {
+		if len(format) == 0 {
+			continue
+		}
+
+		if formatErrorRegex.MatchString(fmt.Sprintf(format, params...)) {
+			errs = append(errs, fmt.Errorf("%w: %d Fparams for \"%s\"", ErrFparamsMismatch, len(fparams), format))
+		}
+	}
+	return errs
+}
+
+//isDialectName returns true if name is the name of a database system
+func isDialectName(name string) bool {
+	for _, dialect := range dialectNames {
+		if dialect == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package godbhelper
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//chainErr the expected ChainError of a validation
+type chainErr struct {
+	chain string
+	query int
+	err   error
+}
+
+//checkValidationError compares err with the expected ChainErrors
+func checkValidationError(t *testing.T, err error, want []chainErr) {
+	t.Helper()
+	if len(want) == 0 {
+		if err != nil {
+			t.Fatalf("got %v, want nil", err)
+		}
+		return
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got %v, want a ValidationError", err)
+	}
+	if len(validationErr.Errors) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(validationErr.Errors), len(want), err)
+	}
+
+	for i, got := range validationErr.Errors {
+		if got.Chain != want[i].chain || got.Query != want[i].query || !errors.Is(got, want[i].err) {
+			t.Errorf("errors[%d] got %v (chain '%s' query %d), want %v (chain '%s' query %d)",
+				i, got, got.Chain, got.Query, want[i].err, want[i].chain, want[i].query)
+		}
+		if !errors.Is(err, want[i].err) {
+			t.Errorf("errors.Is(err, %v) = false", want[i].err)
+		}
+	}
+}
+
+func TestQueryChainValidate(t *testing.T) {
+	noop := func(*DBhelper, *sqlx.Tx) error { return nil }
+
+	tests := []struct {
+		name    string
+		queries []SQLQuery
+		want    []chainErr
+	}{
+		{
+			name: "valid",
+			queries: []SQLQuery{
+				{VersionAdded: "0", QueryString: "CREATE TABLE a (id int)"},
+				{VersionAdded: "0.1", FqueryString: "CREATE TABLE %s (id int)", Fparams: []string{"b"}},
+				{VersionAdded: "0.1", FqueryString: "SELECT '100%%'"},
+				{VersionAdded: "1", Func: noop},
+				{VersionAdded: "2", DownQueryString: "DROP TABLE a"},
+				{VersionAdded: "3", Variants: map[string]QueryVariant{"mysql": {QueryString: "SELECT 1"}}},
+				{Repeatable: true, Name: "v", QueryString: "CREATE VIEW v AS SELECT 1"},
+			},
+		},
+		{
+			name:    "empty query",
+			queries: []SQLQuery{{VersionAdded: "1"}},
+			want:    []chainErr{{chain: "chain", query: 0, err: ErrEmptyQuery}},
+		},
+		{
+			name: "invalid versions",
+			queries: []SQLQuery{
+				{VersionAdded: "1.a", QueryString: "SELECT 1"},
+				{VersionAdded: "-1", QueryString: "SELECT 1"},
+				{VersionAdded: "1.-1", QueryString: "SELECT 1"},
+			},
+			want: []chainErr{
+				{chain: "chain", query: 0, err: ErrInvalidVersion},
+				{chain: "chain", query: 1, err: ErrInvalidVersion},
+				{chain: "chain", query: 2, err: ErrInvalidVersion},
+			},
+		},
+		{
+			name: "ambiguous versions",
+			queries: []SQLQuery{
+				{VersionAdded: "1", QueryString: "SELECT 1"},
+				{VersionAdded: "1", QueryString: "SELECT 2"},
+				{VersionAdded: "1.0", QueryString: "SELECT 3"},
+				{VersionAdded: "v1", QueryString: "SELECT 4"},
+			},
+			want: []chainErr{
+				{chain: "chain", query: 2, err: ErrAmbiguousVersion},
+				{chain: "chain", query: 3, err: ErrAmbiguousVersion},
+			},
+		},
+		{
+			name:    "unnamed repeatable",
+			queries: []SQLQuery{{Repeatable: true, QueryString: "SELECT 1"}},
+			want:    []chainErr{{chain: "chain", query: 0, err: ErrUnnamedRepeatable}},
+		},
+		{
+			name: "repeatable versions aren't checked",
+			queries: []SQLQuery{
+				{VersionAdded: "a", Repeatable: true, Name: "v", QueryString: "SELECT 1"},
+			},
+		},
+		{
+			name: "wrong fparams",
+			queries: []SQLQuery{
+				{VersionAdded: "1", FqueryString: "SELECT %s, %s", Fparams: []string{"a"}},
+				{VersionAdded: "1", FqueryString: "SELECT %s", Fparams: []string{"a", "b"}},
+				{VersionAdded: "1", QueryString: "SELECT 1", FdownQueryString: "DROP TABLE %s"},
+				{VersionAdded: "1", FqueryString: "SELECT %[2]s", Fparams: []string{"a"}},
+			},
+			want: []chainErr{
+				{chain: "chain", query: 0, err: ErrFparamsMismatch},
+				{chain: "chain", query: 1, err: ErrFparamsMismatch},
+				{chain: "chain", query: 2, err: ErrFparamsMismatch},
+				{chain: "chain", query: 3, err: ErrFparamsMismatch},
+			},
+		},
+		{
+			name: "fparams used by the up and down statement",
+			queries: []SQLQuery{
+				{VersionAdded: "1", FqueryString: "CREATE TABLE %s (id int)", FdownQueryString: "DROP TABLE %s", Fparams: []string{"a"}},
+			},
+		},
+		{
+			name: "variants",
+			queries: []SQLQuery{
+				{VersionAdded: "1", QueryString: "SELECT 1", Variants: map[string]QueryVariant{
+					"oracle":   {QueryString: "SELECT 1 FROM dual"},
+					"postgres": {FqueryString: "SELECT %s"},
+					"mssql":    {QueryString: "SELECT 1"},
+				}},
+			},
+			want: []chainErr{
+				{chain: "chain", query: 0, err: ErrInvalidVariant},
+				{chain: "chain", query: 0, err: ErrInvalidVariant},
+				{chain: "chain", query: 0, err: ErrFparamsMismatch},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chain := QueryChain{Name: "chain", Queries: test.queries}
+			checkValidationError(t, chain.Validate(), test.want)
+		})
+	}
+}
+
+func TestDBhelperValidate(t *testing.T) {
+	query := []SQLQuery{{VersionAdded: "1", QueryString: "SELECT 1"}}
+
+	tests := []struct {
+		name   string
+		chains []QueryChain
+		want   []chainErr
+	}{
+		{
+			name:   "valid",
+			chains: []QueryChain{{Name: "a", Queries: query}, {Name: "b", DependsOn: []string{"a"}, Queries: query}},
+		},
+		{
+			name:   "duplicate chain",
+			chains: []QueryChain{{Name: "a", Queries: query}, {Name: "b"}, {Name: "a"}},
+			want:   []chainErr{{chain: "a", query: -1, err: ErrDuplicateChain}},
+		},
+		{
+			name:   "missing dependency",
+			chains: []QueryChain{{Name: "a", DependsOn: []string{"b", "c"}}, {Name: "b"}},
+			want:   []chainErr{{chain: "a", query: -1, err: ErrMissingDependency}},
+		},
+		{
+			name:   "dependency cycle",
+			chains: []QueryChain{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}},
+			want:   []chainErr{{chain: "", query: -1, err: ErrDependencyCycle}},
+		},
+		{
+			name: "every problem",
+			chains: []QueryChain{
+				{Name: "a", DependsOn: []string{"x"}, Queries: []SQLQuery{{VersionAdded: "1"}}},
+				{Name: "b", Queries: []SQLQuery{{VersionAdded: "1", QueryString: "SELECT 1"}, {VersionAdded: "b", QueryString: "SELECT 1"}}},
+			},
+			want: []chainErr{
+				{chain: "a", query: 0, err: ErrEmptyQuery},
+				{chain: "a", query: -1, err: ErrMissingDependency},
+				{chain: "b", query: 1, err: ErrInvalidVersion},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbhelper := NewDBHelper(Sqlite)
+			for _, chain := range test.chains {
+				dbhelper.AddQueryChain(chain)
+			}
+			checkValidationError(t, dbhelper.Validate(), test.want)
+		})
+	}
+}
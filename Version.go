@@ -0,0 +1,157 @@
+package godbhelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//Version a version of a SQLQuery or the VersionStore like "1", "0.21" or "1.2.10".
+//Versions are compared part by part as numbers, so "1.10" is newer than "1.9"
+//and "0.1" is older than "0.10". Missing parts count as 0 and an empty version
+//is the same as "0". Only the first part may be negative
+type Version string
+
+//NoVersion the version of a VersionStore which hasn't applied any query yet
+const NoVersion Version = "-1"
+
+//ParseVersion parses s into a Version
+func ParseVersion(s string) (Version, error) {
+	version := Version(strings.TrimSpace(s))
+	if _, err := version.parts(); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+//legacyVersion converts a float version of older releases into a Version
+func legacyVersion(f float64) Version {
+	return Version(strconv.FormatFloat(f, 'f', -1, 32))
+}
+
+//padFraction pads the fraction of a converted float version with zeros to width digits.
+//Floats of the same width compare like Versions, so 0.3 -> "0.30" stays newer than "0.21"
+func (version Version) padFraction(width int) Version {
+	//Missing parts count as 0 anyway
+	dot := strings.IndexByte(string(version), '.')
+	if dot < 0 {
+		return version
+	}
+
+	digits := len(version) - dot - 1
+	if width <= digits {
+		return version
+	}
+	return version + Version(strings.Repeat("0", width-digits))
+}
+
+//legacyFloat returns version as float32 like older releases compared it.
+//ok is false if version can't be a version of an older release
+func (version Version) legacyFloat() (f float32, ok bool) {
+	f64, err := strconv.ParseFloat(version.String(), 32)
+	return float32(f64), err == nil
+}
+
+//parts returns the numeric parts of version
+func (version Version) parts() ([]int64, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(string(version)), "v")
+	if len(s) == 0 {
+		return []int64{0}, nil
+	}
+
+	fields := strings.Split(s, ".")
+	parts := make([]int64, len(fields))
+	for i, field := range fields {
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil || strings.HasPrefix(field, "+") || (i > 0 && strings.HasPrefix(field, "-")) {
+			return nil, fmt.Errorf("%w: '%s'", ErrInvalidVersion, version)
+		}
+		parts[i] = n
+	}
+
+	return parts, nil
+}
+
+//canonical returns version without trailing zero parts, so
+//equal versions have the same canonical string
+func (version Version) canonical() string {
+	parts, err := version.parts()
+	if err != nil {
+		return string(version)
+	}
+
+	for len(parts) > 1 && parts[len(parts)-1] == 0 {
+		parts = parts[:len(parts)-1]
+	}
+
+	s := make([]string, len(parts))
+	for i, part := range parts {
+		s[i] = strconv.FormatInt(part, 10)
+	}
+	return strings.Join(s, ".")
+}
+
+//Valid returns an error if version can't be parsed
+func (version Version) Valid() error {
+	_, err := version.parts()
+	return err
+}
+
+//Compare returns -1 if version is older than other, 1 if it's newer and 0 if both are equal.
+//Invalid versions are compared as strings
+func (version Version) Compare(other Version) int {
+	a, errA := version.parts()
+	b, errB := other.parts()
+	if errA != nil || errB != nil {
+		return strings.Compare(string(version), string(other))
+	}
+
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int64
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+//Less returns true if version is older than other
+func (version Version) Less(other Version) bool {
+	return version.Compare(other) < 0
+}
+
+//String returns the version as string
+func (version Version) String() string {
+	if len(version) == 0 {
+		return "0"
+	}
+	return string(version)
+}
+
+//UnmarshalJSON reads a version from a string or from a number
+//which is used by QueryChains exported by older releases
+func (version *Version) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*version = Version(s)
+		return nil
+	}
+
+	var f float64
+	if err := json.Unmarshal(b, &f); err != nil {
+		return err
+	}
+	*version = legacyVersion(f)
+	return nil
+}
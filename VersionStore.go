@@ -0,0 +1,361 @@
+package godbhelper
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//legacyChain the chain name of the version which older releases
+//stored for all chains together
+const legacyChain = "*"
+
+//versionTableColumns the columns of the VersionStore
+const versionTableColumns = "(chain VARCHAR(255) NOT NULL PRIMARY KEY, version VARCHAR(64) NOT NULL)"
+
+//initDBVersion creates the tables used to update the database, converts the
+//VersionStore of older releases and loads the versions. Tables are changed,
+//so it has to run while holding the migration lock (see lockMigrations)
+func (dbhelper *DBhelper) initDBVersion() error {
+	//Convert VersionStores of older releases
+	if err := dbhelper.migrateLegacyVersionStore(); err != nil {
+		return err
+	}
+
+	if _, err := dbhelper.DB.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s %s", TableDBVersion, versionTableColumns)); err != nil {
+		return err
+	}
+	if err := dbhelper.initRepeatables(); err != nil {
+		return err
+	}
+	if err := dbhelper.initMigrationHistory(); err != nil {
+		return err
+	}
+	return dbhelper.loadVersions()
+}
+
+//restoreVersions loads the versions without changing the database. There's no version
+//if the VersionStore doesn't exist yet. The version of older releases is loaded as
+//version of the legacyChain, the VersionStore gets converted on the next update
+func (dbhelper *DBhelper) restoreVersions() error {
+	columns, err := dbhelper.columnTypes(TableDBVersion)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := columns["chain"]; ok {
+		return dbhelper.loadVersions()
+	}
+
+	dbhelper.chainVersions = nil
+	dbhelper.CurrentVersion = NoVersion
+	if len(columns) == 0 {
+		return nil
+	}
+
+	versions, err := dbhelper.legacyVersions(columns)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 1 && NoVersion.Less(versions[0]) {
+		dbhelper.setChainVersion(legacyChain, versions[0])
+	}
+	return nil
+}
+
+//loadVersions loads the versions of all chains from the VersionStore into dbhelper
+func (dbhelper *DBhelper) loadVersions() error {
+	var rows []struct {
+		Chain   string  `db:"chain"`
+		Version Version `db:"version"`
+	}
+	if err := dbhelper.DB.Select(&rows, "SELECT chain, version FROM "+TableDBVersion); err != nil {
+		return err
+	}
+
+	dbhelper.chainVersions = nil
+	dbhelper.CurrentVersion = NoVersion
+	for _, row := range rows {
+		dbhelper.setChainVersion(row.Chain, row.Version)
+	}
+
+	return nil
+}
+
+//migrateLegacyVersionStore converts the single version of older releases
+//into a version of the legacyChain. The version gets assigned to every chain
+//on the next update (see adoptLegacyVersion)
+func (dbhelper *DBhelper) migrateLegacyVersionStore() error {
+	columns, err := dbhelper.columnTypes(TableDBVersion)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := columns["chain"]; ok || len(columns) == 0 {
+		return nil
+	}
+
+	versions, err := dbhelper.legacyVersions(columns)
+	if err != nil {
+		return err
+	}
+
+	//The new VersionStore is filled under a temporary name and swapped in
+	//afterwards, so the old version is kept if the conversion fails. DDL
+	//statements commit implicitly on MySQL, so don't rely on the transaction
+	newTable := TableDBVersion + "_new"
+	oldTable := TableDBVersion + "_legacy"
+	for _, query := range []string{
+		"DROP TABLE IF EXISTS " + newTable,
+		"DROP TABLE IF EXISTS " + oldTable,
+		fmt.Sprintf("CREATE TABLE %s %s", newTable, versionTableColumns),
+	} {
+		if _, err = dbhelper.DB.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	if len(versions) == 1 && NoVersion.Less(versions[0]) {
+		query := dbhelper.DB.Rebind(fmt.Sprintf("INSERT INTO %s (chain, version) VALUES (?, ?)", newTable))
+		if _, err = dbhelper.DB.Exec(query, legacyChain, versions[0]); err != nil {
+			return err
+		}
+	}
+
+	if err = dbhelper.swapTable(TableDBVersion, newTable, oldTable); err != nil {
+		return err
+	}
+
+	_, err = dbhelper.DB.Exec("DROP TABLE " + oldTable)
+	return err
+}
+
+//swapTable replaces table by newTable and renames table to oldTable in one step.
+//MySQL renames multiple tables atomically, the other databases in a transaction
+func (dbhelper *DBhelper) swapTable(table, newTable, oldTable string) error {
+	if dbhelper.dbKind == Mysql {
+		_, err := dbhelper.DB.Exec(fmt.Sprintf("RENAME TABLE %s TO %s, %s TO %s", table, oldTable, newTable, table))
+		return err
+	}
+
+	tx, err := dbhelper.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	for _, query := range []string{
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", table, oldTable),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", newTable, table),
+	} {
+		if _, err = tx.Exec(query); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+//legacyVersions reads the version of the VersionStore of older releases
+//which has the given columns
+func (dbhelper *DBhelper) legacyVersions(columns map[string]string) ([]Version, error) {
+	//Versions were stored as float before
+	var versions []Version
+	var err error
+	switch columns["version"] {
+	case "float", "real", "double", "double precision", "numeric", "decimal":
+		var floats []float64
+		err = dbhelper.DB.Select(&floats, "SELECT version FROM "+TableDBVersion)
+		for _, f := range floats {
+			versions = append(versions, legacyVersion(f))
+		}
+	default:
+		err = dbhelper.DB.Select(&versions, "SELECT version FROM "+TableDBVersion)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(versions) > 1 {
+		return nil, ErrVersionStoreTooManyVersions
+	}
+	return versions, nil
+}
+
+//adoptLegacyVersion assigns the version stored by older releases to every
+//registered chain which has no version yet (see legacyChainVersion).
+//Chains added later start from scratch
+func (dbhelper *DBhelper) adoptLegacyVersion() error {
+	versions, err := dbhelper.legacyChainVersions()
+	if err != nil || versions == nil {
+		return err
+	}
+
+	tx, err := dbhelper.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	for chain, version := range versions {
+		if err = dbhelper.saveVersion(tx, chain, version); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if _, err = tx.Exec(dbhelper.DB.Rebind(fmt.Sprintf("DELETE FROM %s WHERE chain = ?", TableDBVersion)), legacyChain); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	delete(dbhelper.chainVersions, legacyChain)
+	for chain, version := range versions {
+		dbhelper.setChainVersion(chain, version)
+	}
+
+	return nil
+}
+
+//legacyChainVersions returns the versions adoptLegacyVersion assigns to the registered
+//chains without version. nil is returned if there's no version of an older release
+func (dbhelper *DBhelper) legacyChainVersions() (map[string]Version, error) {
+	legacy, ok := dbhelper.chainVersions[legacyChain]
+	if !ok {
+		return nil, nil
+	}
+
+	versions := make(map[string]Version)
+	for _, chain := range dbhelper.QueryChains {
+		if _, ok := dbhelper.chainVersions[chain.Name]; ok {
+			continue
+		}
+
+		version, err := dbhelper.legacyChainVersion(chain, legacy)
+		if err != nil {
+			return nil, err
+		}
+
+		//None of the queries of the chain ran
+		if NoVersion.Less(version) {
+			versions[chain.Name] = version
+		}
+	}
+
+	return versions, nil
+}
+
+//legacyChainVersion converts the version stored by older releases into a version of chain.
+//Older releases compared versions as floats, so 0.3 was newer than 0.21. The newest query
+//which was applied by comparing floats becomes the version of the chain. A *LegacyVersionError
+//is returned if the versions of chain are ordered differently as Version, since queries
+//would run again or get skipped otherwise
+func (dbhelper *DBhelper) legacyChainVersion(chain QueryChain, legacy Version) (Version, error) {
+	limit, ok := legacy.legacyFloat()
+	if !ok {
+		return legacy, nil
+	}
+
+	type floatVersion struct {
+		version Version
+		f       float32
+	}
+
+	var floats []floatVersion
+	var others []Version
+	for _, query := range dbhelper.versionedQueries(chain) {
+		if f, ok := query.VersionAdded.legacyFloat(); ok {
+			floats = append(floats, floatVersion{version: query.VersionAdded, f: f})
+		} else {
+			others = append(others, query.VersionAdded)
+		}
+	}
+	sort.SliceStable(floats, func(i, j int) bool {
+		return floats[i].f < floats[j].f
+	})
+
+	version := NoVersion
+	for i, current := range floats {
+		//Equal floats have to be equal versions, bigger floats newer versions
+		if i > 0 {
+			previous := floats[i-1]
+			order := 0
+			if previous.f < current.f {
+				order = -1
+			}
+			if previous.version.Compare(current.version) != order {
+				return "", &LegacyVersionError{Chain: chain.Name, Older: previous.version, Newer: current.version}
+			}
+		}
+
+		if current.f <= limit {
+			version = current.version
+		}
+	}
+
+	//Versions which aren't floats were added after the upgrade
+	for _, other := range others {
+		if !version.Less(other) {
+			return "", &LegacyVersionError{Chain: chain.Name, Older: version, Newer: other}
+		}
+	}
+
+	return version, nil
+}
+
+//ChainVersion returns the version of the chain with the given name.
+//Chains which never ran have NoVersion
+func (dbhelper *DBhelper) ChainVersion(name string) Version {
+	if version, ok := dbhelper.chainVersions[name]; ok {
+		return version
+	}
+
+	//Not yet adopted version of an older release
+	if legacy, ok := dbhelper.chainVersions[legacyChain]; ok {
+		for _, chain := range dbhelper.QueryChains {
+			if chain.Name != name {
+				continue
+			}
+			if version, err := dbhelper.legacyChainVersion(chain, legacy); err == nil {
+				return version
+			}
+		}
+		return legacy
+	}
+
+	return NoVersion
+}
+
+//setChainVersion sets the version of a chain in dbhelper. CurrentVersion
+//is the newest version of all chains
+func (dbhelper *DBhelper) setChainVersion(chain string, version Version) {
+	if dbhelper.chainVersions == nil {
+		dbhelper.chainVersions = make(map[string]Version)
+	}
+	dbhelper.chainVersions[chain] = version
+
+	dbhelper.CurrentVersion = NoVersion
+	for _, v := range dbhelper.chainVersions {
+		if dbhelper.CurrentVersion.Less(v) {
+			dbhelper.CurrentVersion = v
+		}
+	}
+}
+
+//saveVersion writes the version of a chain into the VersionStore using execer.
+//Pass a transaction to store the version together with an update step
+func (dbhelper *DBhelper) saveVersion(execer sqlx.Execer, chain string, version Version) error {
+	if !dbhelper.Options.StoreVersionInDB {
+		return nil
+	}
+
+	if _, err := execer.Exec(dbhelper.DB.Rebind(fmt.Sprintf("DELETE FROM %s WHERE chain = ?", TableDBVersion)), chain); err != nil {
+		return err
+	}
+	_, err := execer.Exec(dbhelper.DB.Rebind(fmt.Sprintf("INSERT INTO %s (chain, version) VALUES (?, ?)", TableDBVersion)), chain, version)
+	return err
+}
@@ -0,0 +1,218 @@
+package godbhelper
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b Version
+		want int
+	}{
+		{a: "1", b: "1", want: 0},
+		{a: "1", b: "2", want: -1},
+		{a: "2", b: "1", want: 1},
+		{a: "0.21", b: "0.3", want: 1},
+		{a: "0.3", b: "0.30", want: -1},
+		{a: "1.9", b: "1.10", want: -1},
+		{a: "0.1", b: "0.10", want: -1},
+		{a: "1", b: "1.0", want: 0},
+		{a: "1.0.0", b: "1", want: 0},
+		{a: "1.2.10", b: "1.2.9", want: 1},
+		{a: "", b: "0", want: 0},
+		{a: "v1.2", b: "1.2", want: 0},
+		{a: " 1.2 ", b: "1.2", want: 0},
+		{a: NoVersion, b: "0", want: -1},
+		{a: NoVersion, b: "", want: -1},
+		{a: "-2", b: NoVersion, want: -1},
+		{a: "10", b: "9", want: 1},
+		{a: "20200101120000", b: "9", want: 1},
+		//Invalid versions are compared as strings
+		{a: "a", b: "b", want: -1},
+		{a: "1.a", b: "1.a", want: 0},
+	}
+
+	for _, test := range tests {
+		if got := test.a.Compare(test.b); got != test.want {
+			t.Errorf("%q.Compare(%q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+		if got := test.b.Compare(test.a); got != -test.want {
+			t.Errorf("%q.Compare(%q) = %d, want %d", test.b, test.a, got, -test.want)
+		}
+		if got := test.a.Less(test.b); got != (test.want < 0) {
+			t.Errorf("%q.Less(%q) = %t, want %t", test.a, test.b, got, test.want < 0)
+		}
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Version
+		invalid bool
+	}{
+		{s: "1", want: "1"},
+		{s: " 0.21 ", want: "0.21"},
+		{s: "v1.2.3", want: "v1.2.3"},
+		{s: "", want: ""},
+		{s: "-1", want: "-1"},
+		{s: "1.-1", invalid: true},
+		{s: "+1", invalid: true},
+		{s: "1..2", invalid: true},
+		{s: "1.", invalid: true},
+		{s: "1.a", invalid: true},
+		{s: "1,2", invalid: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseVersion(test.s)
+		if test.invalid {
+			if !errors.Is(err, ErrInvalidVersion) {
+				t.Errorf("ParseVersion(%q) = %q, %v, want %v", test.s, got, err, ErrInvalidVersion)
+			}
+			continue
+		}
+
+		if err != nil || got != test.want {
+			t.Errorf("ParseVersion(%q) = %q, %v, want %q", test.s, got, err, test.want)
+		}
+	}
+}
+
+func TestLegacyVersion(t *testing.T) {
+	tests := []struct {
+		f    float64
+		want Version
+	}{
+		{f: 0, want: "0"},
+		{f: 1, want: "1"},
+		{f: 0.3, want: "0.3"},
+		{f: 0.21, want: "0.21"},
+		{f: 1.5, want: "1.5"},
+		{f: -1, want: "-1"},
+		//Versions were float32, so the float64 of the database has noise
+		{f: float64(float32(0.3)), want: "0.3"},
+		{f: float64(float32(0.21)), want: "0.21"},
+	}
+
+	for _, test := range tests {
+		if got := legacyVersion(test.f); got != test.want {
+			t.Errorf("legacyVersion(%v) = %q, want %q", test.f, got, test.want)
+		}
+	}
+}
+
+func TestPadFraction(t *testing.T) {
+	tests := []struct {
+		version Version
+		width   int
+		want    Version
+	}{
+		{version: "0.3", width: 2, want: "0.30"},
+		{version: "0.21", width: 2, want: "0.21"},
+		{version: "0.21", width: 1, want: "0.21"},
+		{version: "1.5", width: 3, want: "1.500"},
+		{version: "1", width: 2, want: "1"},
+		{version: "0.3", width: 0, want: "0.3"},
+	}
+
+	for _, test := range tests {
+		if got := test.version.padFraction(test.width); got != test.want {
+			t.Errorf("%q.padFraction(%d) = %q, want %q", test.version, test.width, got, test.want)
+		}
+	}
+}
+
+func TestUnmarshalLegacyQueryChain(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []Version
+	}{
+		{
+			name: "floats keep their order",
+			src:  `{"queries": [{"vs": 0.1}, {"vs": 0.21}, {"vs": 0.3}, {"vs": 1}]}`,
+			want: []Version{"0.10", "0.21", "0.30", "1"},
+		},
+		{
+			name: "strings aren't padded",
+			src:  `{"queries": [{"vs": "0.3"}, {"vs": 0.21}, {"vs": 0.5}]}`,
+			want: []Version{"0.3", "0.21", "0.50"},
+		},
+		{
+			name: "integers only",
+			src:  `{"queries": [{"vs": 0}, {"vs": 2}]}`,
+			want: []Version{"0", "2"},
+		},
+		{
+			name: "missing version",
+			src:  `{"queries": [{"s": "SELECT 1"}, {"vs": 0.25}, {"vs": 0.5}]}`,
+			want: []Version{"", "0.25", "0.50"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var chain QueryChain
+			if err := json.Unmarshal([]byte(test.src), &chain); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(chain.Queries) != len(test.want) {
+				t.Fatalf("got %d queries, want %d", len(chain.Queries), len(test.want))
+			}
+			for i, query := range chain.Queries {
+				if query.VersionAdded != test.want[i] {
+					t.Errorf("queries[%d] got v.%q, want v.%q", i, query.VersionAdded, test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLegacyChainVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		legacy   Version
+		versions []Version
+		want     Version
+		invalid  bool
+	}{
+		{name: "newest applied", legacy: "0.3", versions: []Version{"0.1", "0.2", "0.3", "0.4"}, want: "0.3"},
+		{name: "between versions", legacy: "0.25", versions: []Version{"0.1", "0.2", "0.3"}, want: "0.2"},
+		{name: "nothing applied", legacy: "0.05", versions: []Version{"0.1", "0.2"}, want: NoVersion},
+		{name: "padded fractions", legacy: "0.3", versions: []Version{"0.21", "0.30", "1"}, want: "0.30"},
+		{name: "padded integer", legacy: "1", versions: []Version{"0.21", "0.30", "1.00"}, want: "1.00"},
+		{name: "non-float versions are newer", legacy: "1", versions: []Version{"0.5", "1", "1.2.1"}, want: "1"},
+		{name: "float order differs", legacy: "0.3", versions: []Version{"0.21", "0.3"}, invalid: true},
+		{name: "equal floats differ", legacy: "1", versions: []Version{"0.1", "0.10"}, invalid: true},
+		{name: "non-float version is older", legacy: "1", versions: []Version{"0.5.1", "1"}, invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chain := QueryChain{Name: "chain"}
+			for _, version := range test.versions {
+				chain.Queries = append(chain.Queries, SQLQuery{VersionAdded: version, QueryString: "SELECT 1"})
+			}
+
+			got, err := NewDBHelper(Sqlite).legacyChainVersion(chain, test.legacy)
+			if test.invalid {
+				var legacyErr *LegacyVersionError
+				if !errors.As(err, &legacyErr) || !errors.Is(err, ErrLegacyVersionOrder) {
+					t.Fatalf("got v.%s, %v, want a LegacyVersionError", got, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got v.%s, want v.%s", got, test.want)
+			}
+		})
+	}
+}
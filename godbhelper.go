@@ -3,8 +3,8 @@ package godbhelper
 import (
 	"database/sql"
 	"fmt"
-	"sort"
 	"strconv"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/jmoiron/sqlx"
@@ -16,6 +16,22 @@ type DBhelperOptions struct {
 	StopUpdateOnError bool
 	StoreVersionInDB  bool
 	UseColors         bool
+	TransactionMode   TransactionMode
+	//WarnOnDrift only warns about changed queries which were already applied
+	//instead of failing the update with a DriftError. The drifts are passed
+	//to the ErrHook and returned in UpdateReport.Drifts
+	WarnOnDrift bool
+	//HoldVersionOnError stops updating a chain at its first failed query,
+	//so its version never moves past a failed query
+	HoldVersionOnError bool
+	//LockTimeout the time RunUpdate and RunDowngrade wait for the migration
+	//lock held by other processes. 0 uses DefaultLockTimeout
+	LockTimeout time.Duration
+	//LockStaleAfter the time after which the Sqlite lock of a crashed process
+	//gets removed. The holder refreshes the lock, but a single transaction
+	//running longer blocks refreshing, so use a longer time for slow updates.
+	//0 uses DefaultLockStaleAfter
+	LockStaleAfter time.Duration
 }
 
 //DBhelper the dbhelper object
@@ -24,10 +40,13 @@ type DBhelper struct {
 	dbKind dbsys
 
 	//Versions for upgrading
-	//CurrentVersion the version currently running
-	CurrentVersion float32
+	//CurrentVersion the newest version of all chains
+	CurrentVersion Version
 	//AvailableVersion the version which is newly added
-	AvailableVersion float32
+	AvailableVersion Version
+
+	//versions of the chains by their names
+	chainVersions map[string]Version
 
 	//DBhelper data
 	DB          *sqlx.DB
@@ -40,6 +59,9 @@ type DBhelper struct {
 
 	NextErrHookFunc   ErrHookFunc
 	NextErrHookOption *ErrHookOptions
+
+	//migration hooks by their HookPoint
+	hooks map[HookPoint][]HookFunc
 }
 
 //NewDBHelper the DBhelper constructor NewDBHelper(database, debug, stopUpdateOnError, storeVersionInDB, useColors)
@@ -70,31 +92,6 @@ func NewDBHelper(dbKind dbsys, bv ...bool) *DBhelper {
 	return &dbhelper
 }
 
-func (dbhelper *DBhelper) initDBVersion() error {
-	dbhelper.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version %s)", TableDBVersion, float32Value(dbhelper.dbKind)))
-
-	var c int
-	dbhelper.QueryRow(&c, "SELECT COUNT(*) FROM "+TableDBVersion)
-
-	if c == 0 {
-		dbhelper.Exec(fmt.Sprintf("INSERT INTO %s (version) VALUES (?)", TableDBVersion), -1.0)
-	} else if c > 1 {
-		return ErrVersionStoreTooManyVersions
-	}
-
-	//Load version into dbhelper
-	dbhelper.QueryRow(&dbhelper.CurrentVersion, "SELECT version FROM "+TableDBVersion)
-	return nil
-}
-
-func (dbhelper *DBhelper) saveVersion(version float32) {
-	if dbhelper.Options.StoreVersionInDB {
-		dbhelper.Exec("DELETE FROM " + TableDBVersion)
-		dbhelper.Exec(fmt.Sprintf("INSERT INTO %s (version) VALUES (?)", TableDBVersion), version)
-	}
-	dbhelper.CurrentVersion = version
-}
-
 //SetErrHook sets the error hook function
 func (dbhelper *DBhelper) SetErrHook(hook ErrHookFunc, options ...ErrHookOptions) {
 	if len(options) > 0 {
@@ -258,8 +255,11 @@ func (dbhelper *DBhelper) Open(params ...string) (*DBhelper, error) {
 		}
 	}
 
+	//The tables get created by the first update while holding the migration lock
 	if dbhelper.Options.StoreVersionInDB {
-		dbhelper.initDBVersion()
+		if err := dbhelper.restoreVersions(); err != nil {
+			return dbhelper, err
+		}
 	} else if dbhelper.Options.Debug {
 		fmt.Println("Note: No DBVersion was restored!")
 	}
@@ -310,126 +310,6 @@ func (dbhelper *DBhelper) AddQueryChain(chain QueryChain) *DBhelper {
 	return dbhelper
 }
 
-//RunUpdate updates new sql queries
-//RunUpdate(fullUpdate, dropAllTables bool)
-func (dbhelper *DBhelper) RunUpdate(options ...bool) error {
-	if !dbhelper.Options.StoreVersionInDB {
-		return ErrCantStoreVersionInDB
-	}
-	dbhelper.checkColors()
-
-	//Check options
-	var fullUpdate, dropAllTables bool
-	for i, v := range options {
-		switch i {
-		case 0:
-			fullUpdate = v
-		case 1:
-			dropAllTables = v
-		}
-	}
-
-	//Print info
-	if dbhelper.Options.Debug {
-		var add string
-		if fullUpdate {
-			add = "full"
-		}
-		fmt.Printf("Updating database %s\n", add)
-	}
-
-	//Set current version to 0 to run every query
-	if fullUpdate {
-		dbhelper.CurrentVersion = 0
-	}
-
-	if dropAllTables {
-		//TODO
-	}
-
-	var c int
-	noError := true
-	newVersion := dbhelper.CurrentVersion
-
-	if dbhelper.Options.Debug {
-		fmt.Println()
-	}
-
-	//Sort QueryChains to run in correct order
-	sort.SliceStable(dbhelper.QueryChains, func(i, j int) bool {
-		return dbhelper.QueryChains[i].Order < dbhelper.QueryChains[j].Order
-	})
-
-	for _, chain := range dbhelper.QueryChains {
-		if dbhelper.Options.Debug {
-			color.New(color.Underline).Println("chain:", chain.Name)
-		}
-
-		//Sort Queries in current chain by version to run in correct order
-		sort.SliceStable(chain.Queries, func(i, j int) bool {
-			return chain.Queries[i].VersionAdded < chain.Queries[j].VersionAdded
-		})
-
-		countSuccesfulQueries := 0
-		for _, query := range chain.Queries {
-			if len(query.QueryString)+len(query.FqueryString) == 0 {
-				continue
-			}
-
-			if query.VersionAdded > dbhelper.CurrentVersion {
-				if dbhelper.Options.Debug {
-					q := fmt.Sprintf(query.FqueryString, stringArrToInterface(query.Fparams)...)
-					if len(query.FqueryString) == 0 {
-						q = query.QueryString
-					}
-					fmt.Print("v.", query.VersionAdded, ":\t\"", q, "\"", query.Params)
-				}
-
-				var err error
-				if len(query.FqueryString) > 0 {
-					_, err = dbhelper.Execf(query.FqueryString, query.Fparams, stringArrToInterface(query.Params)...)
-				} else {
-					_, err = dbhelper.Exec(query.QueryString, stringArrToInterface(query.Params)...)
-				}
-				if err != nil {
-					fmt.Printf(" -> %s\n", color.New(color.FgRed).SprintFunc()(" ERROR: "+err.Error()))
-					if dbhelper.Options.StopUpdateOnError {
-						return err
-					}
-					noError = false
-				}
-
-				if query.VersionAdded > newVersion {
-					newVersion = query.VersionAdded
-				}
-
-				if dbhelper.Options.Debug && err == nil {
-					fmt.Printf(" -> %s\n", color.New(color.FgGreen).SprintFunc()("success"))
-					countSuccesfulQueries++
-				}
-
-				c++
-			}
-		}
-
-		if dbhelper.Options.Debug && countSuccesfulQueries > 0 {
-			fmt.Println()
-		}
-	}
-
-	if dbhelper.Options.Debug {
-		msg := "Updated %d Database queries with errors\n"
-		if noError {
-			msg = "Successfully updated %d Database queries\n"
-		}
-		fmt.Printf(msg, c)
-	}
-
-	//Save new version
-	dbhelper.saveVersion(newVersion)
-	return nil
-}
-
 func (dbhelper *DBhelper) checkColors() {
 	color.NoColor = !dbhelper.Options.UseColors
 }